@@ -13,6 +13,7 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -25,7 +26,10 @@ import (
 	"github.com/maksim-paskal/hcloud-k8s-ctl/internal"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/api"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/output"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/version"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -48,7 +52,10 @@ func main() { //nolint:cyclop,funlen
 
 	log.Infof("Starting %s...", gitVersion)
 
+	// stdout is used for action results
+	log.SetOutput(os.Stderr)
 	log.SetReportCaller(true)
+	log.AddHook(redact.NewHook())
 
 	if err := internal.Init(); err != nil {
 		log.WithError(err).Fatal("error loading config")
@@ -61,6 +68,11 @@ func main() { //nolint:cyclop,funlen
 
 	log.SetLevel(logLevel)
 
+	outputFormat, err := output.ParseFormat(*config.Get().CliArgs.Output)
+	if err != nil {
+		log.WithError(err).Fatal()
+	}
+
 	if *checkNewVersion {
 		if err := version.CheckLatest(ctx, gitVersion); err != nil {
 			if strings.Contains(err.Error(), "new version available") {
@@ -75,21 +87,34 @@ func main() { //nolint:cyclop,funlen
 		log.WithError(err).Fatal("error checking config")
 	}
 
-	applicationAPI, err := api.NewApplicationAPI(ctx)
-	if err != nil {
-		log.Fatal(err)
+	applicationAPI := api.NewOfflineApplicationAPI()
+
+	if !config.Offline() {
+		applicationAPI, err = api.NewApplicationAPI(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
+	// action result printed to stdout
+	var (
+		result   interface{}
+		exitCode int
+	)
+
 	switch strings.ToLower(*config.Get().CliArgs.Action) {
 	case "create":
-		err = applicationAPI.NewCluster(ctx)
+		result, err = applicationAPI.NewCluster(ctx)
 		if err != nil {
 			log.WithError(err).Fatal()
 		}
 	case "delete":
 		applicationAPI.DeleteCluster(ctx)
 	case "list-configurations":
-		applicationAPI.ListConfigurations(ctx)
+		result, err = applicationAPI.ListConfigurations(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
 	case "patch-cluster":
 		err = applicationAPI.PatchClusterDeployment(ctx)
 		if err != nil {
@@ -100,16 +125,30 @@ func main() { //nolint:cyclop,funlen
 			log.Fatal("add -adhoc.command argument")
 		}
 
-		applicationAPI.ExecuteAdHoc(
-			ctx,
-			*config.Get().CliArgs.AdhocUser,
-			*config.Get().CliArgs.AdhocCommand,
-			*config.Get().CliArgs.AdhocMasters,
-			*config.Get().CliArgs.AdhocWorkers,
-			*config.Get().CliArgs.AdhocCopyNewFile,
-		)
+		adhocResults, err := applicationAPI.ExecuteAdHoc(ctx, api.AdhocOptions{
+			User:           *config.Get().CliArgs.AdhocUser,
+			Command:        *config.Get().CliArgs.AdhocCommand,
+			RunOnMasters:   *config.Get().CliArgs.AdhocMasters,
+			RunOnWorkers:   *config.Get().CliArgs.AdhocWorkers,
+			CopyNewScripts: *config.Get().CliArgs.AdhocCopyNewFile,
+			Selector:       *config.Get().CliArgs.AdhocSelector,
+			Parallel:       *config.Get().CliArgs.AdhocParallel,
+			Timeout:        *config.Get().CliArgs.AdhocTimeout,
+			FailFast:       *config.Get().CliArgs.AdhocFailFast,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if failed := adhocResults.Failed(); failed > 0 {
+			log.Errorf("Adhoc failed on %d of %d servers", failed, len(adhocResults))
+
+			exitCode = 1
+		}
+
+		result = adhocResults
 	case "upgrade-controlplane":
-		applicationAPI.UpgradeControlPlane(ctx)
+		applicationAPI.UpgradeControlPlane(ctx, *config.Get().CliArgs.UpgradeControlPlaneSkipBackup)
 	case "create-firewall":
 		err = applicationAPI.CreateFirewall(
 			ctx,
@@ -119,6 +158,142 @@ func main() { //nolint:cyclop,funlen
 		if err != nil {
 			log.Fatal(err)
 		}
+	case "update-loadbalancer":
+		err = applicationAPI.UpdateLoadBalancer(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "build-image":
+		imageID, err := applicationAPI.BuildImage(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result = api.Resources{{Type: "image", ID: imageID}}
+	case "backup-etcd":
+		result, err = applicationAPI.BackupEtcd(
+			ctx,
+			*config.Get().CliArgs.BackupEtcdOutput,
+			*config.Get().CliArgs.BackupEtcdKeep,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "restore-etcd":
+		if len(*config.Get().CliArgs.RestoreEtcdSnapshot) == 0 {
+			log.Fatal("add -restore-etcd.snapshot argument")
+		}
+
+		err = applicationAPI.RestoreEtcd(ctx, *config.Get().CliArgs.RestoreEtcdSnapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "get-kubeconfig":
+		err = applicationAPI.GetKubeconfig(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "issue-kubeconfig":
+		err = applicationAPI.IssueKubeconfig(
+			ctx,
+			*config.Get().CliArgs.IssueKubeconfigUser,
+			*config.Get().CliArgs.IssueKubeconfigGroup,
+			*config.Get().CliArgs.IssueKubeconfigTTL,
+			*config.Get().CliArgs.IssueKubeconfigOutput,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "ssh":
+		var forwards []string
+
+		if forward := *config.Get().CliArgs.SSHForward; len(forward) > 0 {
+			forwards = strings.Split(forward, ",")
+		}
+
+		exitCode, err = applicationAPI.SSH(ctx, api.SSHOptions{
+			Node:     *config.Get().CliArgs.SSHNode,
+			User:     *config.Get().CliArgs.SSHUser,
+			Forwards: forwards,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "copy":
+		copyResults, err := applicationAPI.Copy(ctx, api.CopyOptions{
+			Source:      *config.Get().CliArgs.CopySource,
+			Destination: *config.Get().CliArgs.CopyDestination,
+			Selector:    *config.Get().CliArgs.CopySelector,
+			Parallel:    *config.Get().CliArgs.CopyParallel,
+			Mode:        *config.Get().CliArgs.CopyMode,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if failed := copyResults.Failed(); failed > 0 {
+			log.Errorf("Copy failed on %d of %d servers", failed, len(copyResults))
+
+			exitCode = 1
+		}
+
+		result = copyResults
+	case "collect-diagnostics":
+		diagnostics, err := applicationAPI.CollectDiagnostics(ctx, api.DiagnosticsOptions{
+			Selector: *config.Get().CliArgs.CollectDiagnosticsSelector,
+			Output:   *config.Get().CliArgs.CollectDiagnosticsOutput,
+			Since:    *config.Get().CliArgs.CollectDiagnosticsSince,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if failed := diagnostics.Failed(); failed > 0 {
+			log.Errorf("Diagnostics not collected from %d of %d servers", failed, len(diagnostics.Servers))
+
+			exitCode = 1
+		}
+
+		result = diagnostics
+	case "status":
+		result, err = applicationAPI.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "drain":
+		err = applicationAPI.Drain(
+			ctx,
+			*config.Get().CliArgs.DrainNode,
+			*config.Get().CliArgs.DrainTimeout,
+			*config.Get().CliArgs.DrainUncordon,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "gc":
+		gcResults, err := applicationAPI.GC(ctx, api.GCOptions{
+			MinAge: *config.Get().CliArgs.GCMinAge,
+			Delete: *config.Get().CliArgs.GCDelete,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if failed := gcResults.Failed(); failed > 0 {
+			log.Errorf("Failed to delete %d of %d orphans", failed, len(gcResults))
+
+			exitCode = 1
+		}
+
+		result = gcResults
+	case "estimate-cost":
+		result, err = applicationAPI.EstimateCost(ctx, api.EstimateCostOptions{
+			Pricing:     *config.Get().CliArgs.EstimateCostPricing,
+			SavePricing: *config.Get().CliArgs.EstimateCostSavePricing,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
 	case "save-full-config":
 		err = config.SaveConfig(*config.Get().CliArgs.SaveConfigPath)
 		if err != nil {
@@ -127,6 +302,34 @@ func main() { //nolint:cyclop,funlen
 	default:
 		log.Fatal("unknown action")
 	}
+
+	if result != nil {
+		if err := printResult(outputFormat, result); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := applicationAPI.Close(); err != nil {
+		log.WithError(err).Warn()
+	}
+
+	os.Exit(exitCode)
+}
+
+func printResult(format output.Format, result interface{}) error {
+	if format == output.FormatText {
+		var b bytes.Buffer
+
+		if err := output.Write(&b, output.FormatYAML, result); err != nil {
+			return errors.Wrap(err, "error formatting result")
+		}
+
+		log.Infof("Result:\n%s", b.String())
+
+		return nil
+	}
+
+	return errors.Wrap(output.Write(os.Stdout, format, result), "error printing result")
 }
 
 func getInterruptionContext() context.Context {
@@ -106,9 +106,6 @@ func Test(t *testing.T) { //nolint:funlen,paralleltest,cyclop
 				},
 			}
 
-			// ignore branch in config, set it to current
-			config.SetServersInitParams()
-
 			config.Get().Deployments = extraDeployments
 			config.Get().KubeConfigPath = tmpFile.Name()
 
@@ -123,7 +120,7 @@ func Test(t *testing.T) { //nolint:funlen,paralleltest,cyclop
 
 			t.Log("Creating cluster...")
 
-			if err := applicationAPI.NewCluster(ctx); err != nil {
+			if _, err := applicationAPI.NewCluster(ctx); err != nil {
 				t.Fatal(err)
 			}
 
@@ -0,0 +1,215 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/fanout"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	adhocSelectorName      = "name:"
+	adhocSelectorNodeGroup = "node-group:"
+)
+
+type AdhocOptions struct {
+	User           string
+	Command        string
+	RunOnMasters   bool
+	RunOnWorkers   bool
+	CopyNewScripts bool
+	// node-group:<name>, name:<regex> or hcloud label selector, overrides RunOnMasters and RunOnWorkers
+	Selector string
+	// maximum number of servers executing command at same time, 0 is unlimited
+	Parallel int
+	// command timeout on each server, 0 is unlimited
+	Timeout time.Duration
+	// do not start command on other servers after first failure
+	FailFast bool
+}
+
+func (api *ApplicationAPI) ExecuteAdHoc(ctx context.Context, opts AdhocOptions) (AdhocResults, error) { //nolint:funlen,lll
+	log.Info("Executing adhoc...")
+
+	if len(opts.User) > 0 {
+		api.sshRootUser = opts.User
+	}
+
+	allServers, err := api.selectAdhocServers(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allServers) == 0 {
+		return nil, errNoServersFound
+	}
+
+	log.Infof("Servers found: %d", len(allServers))
+
+	tasks := make([]fanout.Task, 0, len(allServers))
+	serverIPs := make(map[string]string, len(allServers))
+
+	for _, server := range allServers {
+		serverIP := server.PublicNet.IPv4.IP.String()
+		serverIPs[server.Name] = serverIP
+
+		tasks = append(tasks, fanout.Task{
+			Name: server.Name,
+			Run: func(ctx context.Context) (string, string, error) {
+				if opts.CopyNewScripts {
+					if err := api.downloadNewScripts(server.Name, serverIP); err != nil {
+						return "", "", err
+					}
+				}
+
+				return api.execCommandAsContext(ctx, api.sshRootUser, serverIP, opts.Command)
+			},
+		})
+	}
+
+	log.Infof("Start executing command on selected nodes, parallel=%d", opts.Parallel)
+
+	fanoutResults := fanout.Run(ctx, tasks, fanout.Options{
+		Parallel: opts.Parallel,
+		Timeout:  opts.Timeout,
+		FailFast: opts.FailFast,
+	})
+
+	results := make(AdhocResults, 0, len(fanoutResults))
+
+	for _, fanoutResult := range fanoutResults {
+		result := newAdhocResult(serverIPs[fanoutResult.Name], fanoutResult)
+
+		log := log.WithField("server", result.Server)
+
+		if result.Status == AdhocStatusOK {
+			log.Infof("%s -> %s in %s", result.Server, result.Status, result.Duration)
+		} else {
+			log.Errorf("%s -> %s exitCode=%d %s", result.Server, result.Status, result.ExitCode, result.Error)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// selectAdhocServers returns cluster servers for adhoc command.
+func (api *ApplicationAPI) selectAdhocServers(ctx context.Context, opts AdhocOptions) ([]*hcloud.Server, error) { //nolint:cyclop,lll
+	runOnWorkers := opts.RunOnWorkers || len(opts.Selector) > 0
+	runOnMasters := opts.RunOnMasters || len(opts.Selector) > 0
+
+	var allServers []*hcloud.Server
+
+	if runOnWorkers {
+		log.Info("Get worker nodes...")
+
+		workerServers, err := api.listServers(ctx, nodeGroupSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		allServers = append(allServers, workerServers...)
+	}
+
+	if runOnMasters {
+		log.Info("Get master nodes...")
+
+		masterServers, err := api.listServers(ctx, api.getMasterLabels())
+		if err != nil {
+			return nil, err
+		}
+
+		allServers = append(allServers, masterServers...)
+	}
+
+	var match func(server *hcloud.Server) bool
+
+	switch selector := opts.Selector; {
+	case len(selector) == 0:
+		return allServers, nil
+	case strings.HasPrefix(selector, adhocSelectorName):
+		nameRe, err := regexp.Compile(strings.TrimPrefix(selector, adhocSelectorName))
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing server name regexp")
+		}
+
+		match = func(server *hcloud.Server) bool {
+			return nameRe.MatchString(server.Name)
+		}
+	case strings.HasPrefix(selector, adhocSelectorNodeGroup):
+		nodeGroup := strings.TrimPrefix(selector, adhocSelectorNodeGroup)
+
+		match = func(server *hcloud.Server) bool {
+			return server.Labels[nodeGroupSelector] == nodeGroup
+		}
+	default:
+		selectedServers, err := api.listServers(ctx, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		selected := make(map[int64]bool, len(selectedServers))
+
+		for _, server := range selectedServers {
+			selected[server.ID] = true
+		}
+
+		match = func(server *hcloud.Server) bool {
+			return selected[server.ID]
+		}
+	}
+
+	result := make([]*hcloud.Server, 0)
+
+	for _, server := range allServers {
+		if match(server) {
+			result = append(result, server)
+		}
+	}
+
+	return result, nil
+}
+
+// newAdhocResult returns command result, exit code is -1 if command was not executed.
+func newAdhocResult(ip string, fanoutResult fanout.Result) AdhocResult {
+	result := AdhocResult{
+		Server:   fanoutResult.Name,
+		IP:       ip,
+		Status:   fanoutResult.Status,
+		Duration: fanoutResult.Duration.Round(time.Millisecond).String(),
+		Stdout:   fanoutResult.Stdout,
+		Stderr:   fanoutResult.Stderr,
+	}
+
+	if err := fanoutResult.Err; err != nil {
+		result.Error = err.Error()
+		result.ExitCode = -1
+
+		var exitError *ssh.ExitError
+
+		if errors.As(err, &exitError) {
+			result.ExitCode = exitError.ExitStatus()
+		}
+	}
+
+	return result
+}
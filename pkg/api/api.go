@@ -17,14 +17,20 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
+	"io/fs"
 	"net"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/bundle"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/drain"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/stream"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/scripts"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
@@ -40,9 +46,15 @@ const (
 
 type ApplicationAPI struct {
 	hcloudClient      *hcloud.Client
-	masterClusterJoin string
 	clusterKubeConfig string
 	sshRootUser       string
+	scriptsBundle     *bundle.Bundle
+	// live output of remote commands, nil if disabled
+	output *stream.Output
+	// servers by IP, used as output prefix and for host key pinning
+	servers sync.Map
+	// known hosts file is updated from many connections
+	knownHostsMutex sync.Mutex
 }
 
 func NewApplicationAPI(ctx context.Context) (*ApplicationAPI, error) {
@@ -56,11 +68,90 @@ func NewApplicationAPI(ctx context.Context) (*ApplicationAPI, error) {
 		return &api, err
 	}
 
+	scriptsLayers := []fs.FS{scripts.FS()}
+
+	if scriptsDir := config.Get().ScriptsDir; len(scriptsDir) > 0 {
+		log.Infof("Using scripts overrides from %s", scriptsDir)
+
+		scriptsLayers = append(scriptsLayers, os.DirFS(scriptsDir))
+	}
+
+	templateValues := make(map[string]interface{})
+
+	if err := yaml.Unmarshal(api.getDeploymentValues(), &templateValues); err != nil {
+		return &api, errors.Wrap(err, "error reading scripts values")
+	}
+
+	scriptsBundle, err := bundle.NewWithTemplates(bundle.Templates{
+		Files:  templatedScripts,
+		Values: templateValues,
+	}, scriptsLayers...)
+	if err != nil {
+		return &api, errors.Wrap(err, "error creating scripts bundle")
+	}
+
+	log.Debugf("scripts bundle checksum=%s", scriptsBundle.Checksum)
+
+	api.scriptsBundle = scriptsBundle
+
 	api.sshRootUser = config.Get().ServerComponents.Ubuntu.UserName
 
+	if err := api.initOutput(); err != nil {
+		return &api, err
+	}
+
 	return &api, nil
 }
 
+// initOutput enables streaming of remote commands output to stderr and log files.
+func (api *ApplicationAPI) initOutput() error {
+	var (
+		out    io.Writer
+		runDir string
+	)
+
+	if *config.Get().CliArgs.LogStream {
+		out = os.Stderr
+	}
+
+	if baseDir := *config.Get().CliArgs.LogRunDir; len(baseDir) > 0 {
+		runDir = stream.RunDir(baseDir, *config.Get().CliArgs.Action)
+
+		log.Infof("Saving servers output to %s", runDir)
+	}
+
+	if out == nil && len(runDir) == 0 {
+		return nil
+	}
+
+	output, err := stream.NewOutput(out, runDir)
+	if err != nil {
+		return errors.Wrap(err, "error creating output")
+	}
+
+	api.output = output
+
+	return nil
+}
+
+// Close closes servers log files.
+func (api *ApplicationAPI) Close() error {
+	if api.output == nil {
+		return nil
+	}
+
+	return errors.Wrap(api.output.Close(), "error closing output")
+}
+
+// serverName returns server name for output prefix, IP if server is unknown.
+func (api *ApplicationAPI) serverName(ipAddress string) string {
+	if server, ok := api.servers.Load(ipAddress); ok {
+		return server.(*hcloud.Server).Name //nolint:forcetypeassert
+	}
+
+	return ipAddress
+}
+
 func (api *ApplicationAPI) validateConfig(ctx context.Context) error {
 	log.Info("Validating config...")
 
@@ -85,42 +176,18 @@ func (api *ApplicationAPI) validateConfig(ctx context.Context) error {
 	return nil
 }
 
-func (api *ApplicationAPI) saveKubeconfig() error {
-	log.Info("kubeconfig=\n" + api.clusterKubeConfig)
-	log.Infof("Saving kubeconfig to %s", config.Get().KubeConfigPath)
-
-	err := os.WriteFile(
-		config.Get().KubeConfigPath,
-		[]byte(api.clusterKubeConfig),
-		kubeconfigFileMode,
-	)
-	if err != nil {
-		return errors.Wrap(err, "error creating file")
-	}
-
-	return nil
-}
-
 func (api *ApplicationAPI) getCommonExecCommand() string {
-	return fmt.Sprintf(commonExecCommand,
-		config.Get().MasterServers.ServersInitParams.TarGz,
-		config.Get().MasterServers.ServersInitParams.Folder,
-		api.getDeploymentValues(),
-	)
+	return fmt.Sprintf(commonExecCommand, uploadDir, api.scriptsBundle.Checksum)
 }
 
-func (api *ApplicationAPI) getCommonInstallCommand() string {
-	return api.getCommonExecCommand() + `
-
-/root/scripts/common-install.sh
-
-`
+func getCommonInstallCommand(role string) string {
+	return fmt.Sprintf(commonInstallCommand, role)
 }
 
 func (api *ApplicationAPI) getInitMasterCommand(loadBalancerIP string) string {
-	return api.getCommonExecCommand() + `
-
+	return `
 export MASTER_LB=` + loadBalancerIP + `
+export NODE_ROLE=` + config.NodeRoleFirstMaster + `
 
 /root/scripts/init-master.sh
 `
@@ -149,6 +216,10 @@ func (api *ApplicationAPI) waitForLoadBalancer(ctx context.Context, loadBalancer
 }
 
 func (api *ApplicationAPI) waitForServer(ctx context.Context, server string) (string, error) {
+	return api.waitForServerAs(ctx, api.sshRootUser, server)
+}
+
+func (api *ApplicationAPI) waitForServerAs(ctx context.Context, user string, server string) (string, error) {
 	masterServer, _, err := api.hcloudClient.Server.Get(ctx, server)
 	if err != nil {
 		return "", errors.Wrap(err, "error in server get")
@@ -167,7 +238,9 @@ func (api *ApplicationAPI) waitForServer(ctx context.Context, server string) (st
 		return "", errors.Wrap(err, "serverIP ip null")
 	}
 
-	_, _, err = api.execCommand(string(serverIP), "date")
+	api.servers.Store(string(serverIP), masterServer)
+
+	_, _, err = api.execCommandAsContext(quietContext(ctx), user, string(serverIP), "date")
 	if err != nil {
 		return "", errors.Wrap(err, "error executing command")
 	}
@@ -175,11 +248,89 @@ func (api *ApplicationAPI) waitForServer(ctx context.Context, server string) (st
 	return string(serverIP), nil
 }
 
+// installJoiningMasters runs common install on other master nodes in background,
+// while first master node is initializing.
+func (api *ApplicationAPI) installJoiningMasters(ctx context.Context) map[string]chan error {
+	results := make(map[string]chan error)
+
+	// servers are fresh, use bootstrap user for all of them
+	user := api.getBootstrapUser()
+
+	for i := 2; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		result := make(chan error, 1)
+		results[serverName] = result
+
+		go func() {
+			result <- api.installMasterNode(ctx, user, serverName)
+		}()
+	}
+
+	return results
+}
+
+func (api *ApplicationAPI) installMasterNode(ctx context.Context, user string, server string) error {
+	log := log.WithField("server", server)
+
+	retryCount := 0
+
+	for {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "context error")
+		}
+
+		if retryCount > config.Get().MasterServers.RetryTimeLimit {
+			return errRetryLimitReached
+		}
+
+		if retryCount > 0 {
+			utils.SleepContext(ctx, config.Get().MasterServers.WaitTimeInRetry)
+		}
+
+		retryCount++
+
+		log.Infof("Waiting for server... try=%03d", retryCount)
+
+		serverIP, err := api.waitForServerAs(ctx, user, server)
+		if err != nil {
+			log.WithError(err).Debug()
+
+			continue
+		}
+
+		if err := api.installScripts(user, serverIP); err != nil {
+			log.WithError(err).Error()
+
+			continue
+		}
+
+		log.Info("Executing common install...")
+
+		stdout, stderr, err := api.execCommandAs(user, serverIP, getCommonInstallCommand(config.NodeRoleJoiningMaster))
+		if err != nil {
+			return errors.Wrap(err, stderr)
+		}
+
+		log.Debugf(debugStdout, stdout)
+		log.Debugf(debugStderr, stderr)
+
+		return nil
+	}
+}
+
 func (api *ApplicationAPI) joinToMasterNodes(ctx context.Context, server string) error {
 	log := log.WithField("server", server)
 
 	log.Infof("Join server to master nodes...")
 
+	joinCommand, token, err := api.createJoinCommand(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error creating join command")
+	}
+
+	defer api.deleteJoinToken(ctx, token)
+
 	// join to cluster
 	retryCount := 0
 
@@ -209,7 +360,7 @@ func (api *ApplicationAPI) joinToMasterNodes(ctx context.Context, server string)
 
 		log.Info(executingCommand)
 
-		stdout, stderr, err := api.execCommand(serverIP, api.masterClusterJoin)
+		stdout, stderr, err := api.execCommand(serverIP, joinCommand)
 		if err != nil {
 			log.WithError(err).Error(stderr)
 
@@ -285,14 +436,28 @@ func (api *ApplicationAPI) postInstall(ctx context.Context, copyNewScripts bool)
 		break
 	}
 
+	if err := api.annotateMasters(ctx); err != nil {
+		return errors.Wrap(err, "error annotating master nodes")
+	}
+
+	if err := api.installJoinTokenRefresh(ctx, copyNewScripts); err != nil {
+		return errors.Wrap(err, "error installing join token refresh")
+	}
+
+	// new scripts are already uploaded to all master nodes
+	if config.Get().Backup.Enabled() {
+		if err := api.installEtcdBackup(ctx, false); err != nil {
+			return errors.Wrap(err, "error installing etcd backup")
+		}
+	}
+
 	return nil
 }
 
 func (api *ApplicationAPI) initFirstMasterNode(ctx context.Context) error { //nolint:funlen
 	log.Info("Init first master node...")
 
-	// hetzner cloud default user is root
-	api.sshRootUser = "root"
+	api.sshRootUser = api.getBootstrapUser()
 
 	serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, 1)
 
@@ -331,6 +496,12 @@ func (api *ApplicationAPI) initFirstMasterNode(ctx context.Context) error { //no
 			continue
 		}
 
+		if err = api.downloadNewScripts(serverName, serverIP); err != nil {
+			log.WithError(err).Error()
+
+			continue
+		}
+
 		log.Info(executingCommand)
 
 		stdout, stderr, err := api.execCommand(serverIP, api.getInitMasterCommand(loadBalancerIP))
@@ -343,20 +514,11 @@ func (api *ApplicationAPI) initFirstMasterNode(ctx context.Context) error { //no
 		log.Debugf(debugStdout, stdout)
 		log.Debugf(debugStderr, stderr)
 
-		log.Info("Get join command...")
-
 		api.sshRootUser = config.Get().ServerComponents.Ubuntu.UserName
 
-		stdout, stderr, err = api.execCommand(serverIP, "cat /root/scripts/join-master.sh")
-		if err != nil {
-			log.WithError(err).Fatal(stderr)
-		}
-
-		api.masterClusterJoin = stdout
-
 		log.Info("Get kubeconfig..")
 
-		stdout, stderr, err = api.execCommand(serverIP, "cat /etc/kubernetes/admin.conf")
+		stdout, stderr, err = api.execCommandQuiet(serverIP, "cat /etc/kubernetes/admin.conf")
 		if err != nil {
 			log.WithError(err).Fatal(stderr)
 		}
@@ -369,77 +531,6 @@ func (api *ApplicationAPI) initFirstMasterNode(ctx context.Context) error { //no
 	return nil
 }
 
-func (api *ApplicationAPI) createLoadBalancer(ctx context.Context) error {
-	log.Info("Creating loadbalancer...")
-
-	k8sLoadBalancerType, _, err := api.hcloudClient.LoadBalancerType.Get(
-		ctx,
-		config.Get().MasterLoadBalancer.LoadBalancerType,
-	)
-	if err != nil {
-		return errors.Wrap(err, "could not get loadbalancer type")
-	}
-
-	k8sLocation, _, err := api.hcloudClient.Location.Get(ctx, config.Get().Location)
-	if err != nil {
-		return errors.Wrap(err, "could not get location")
-	}
-
-	k8sNetwork, _, err := api.hcloudClient.Network.Get(ctx, config.Get().ClusterName)
-	if err != nil {
-		return errors.Wrap(err, "could not get network")
-	}
-
-	ListenPort := config.Get().MasterLoadBalancer.ListenPort
-	DestinationPort := config.Get().MasterLoadBalancer.DestinationPort
-
-	k8sService := hcloud.LoadBalancerCreateOptsService{
-		Protocol:        hcloud.LoadBalancerServiceProtocolTCP,
-		ListenPort:      &ListenPort,
-		DestinationPort: &DestinationPort,
-		HealthCheck: &hcloud.LoadBalancerCreateOptsServiceHealthCheck{
-			Protocol: hcloud.LoadBalancerServiceProtocolHTTP,
-			Port:     &ListenPort,
-			Interval: hcloud.Ptr(hcloudLoadBalancerInterval),
-			Timeout:  hcloud.Ptr(hcloudLoadBalancerTimeout),
-			Retries:  hcloud.Ptr(hcloudLoadBalancerRetries),
-			HTTP: &hcloud.LoadBalancerCreateOptsServiceHealthCheckHTTP{
-				Path:        hcloud.Ptr("/healthz"),
-				StatusCodes: []string{"2??"},
-				TLS:         hcloud.Ptr(true),
-			},
-		},
-	}
-
-	_, _, err = api.hcloudClient.LoadBalancer.Create(ctx, hcloud.LoadBalancerCreateOpts{
-		Name:             config.Get().ClusterName,
-		LoadBalancerType: k8sLoadBalancerType,
-		Location:         k8sLocation,
-		Network:          k8sNetwork,
-		Services:         []hcloud.LoadBalancerCreateOptsService{k8sService},
-	})
-	if err != nil {
-		return errors.Wrap(err, "could not create loadbalancer")
-	}
-
-	return nil
-}
-
-func (api *ApplicationAPI) attachToBalancer(ctx context.Context, server hcloud.ServerCreateResult, balancer *hcloud.LoadBalancer) error { //nolint:lll
-	usePrivateIP := true
-	k8sTargetServer := hcloud.LoadBalancerAddServerTargetOpts{
-		Server:       server.Server,
-		UsePrivateIP: &usePrivateIP,
-	}
-
-	_, _, err := api.hcloudClient.LoadBalancer.AddServerTarget(ctx, balancer, k8sTargetServer)
-	if err != nil {
-		return errors.Wrap(err, "could not attach server to loadbalancer")
-	}
-
-	return nil
-}
-
 func (api *ApplicationAPI) createServer(ctx context.Context) error { //nolint:funlen,cyclop
 	log.Info("Creating servers...")
 
@@ -452,13 +543,9 @@ func (api *ApplicationAPI) createServer(ctx context.Context) error { //nolint:fu
 		return errors.Errorf("server type %s not found", config.Get().MasterServers.ServerType)
 	}
 
-	serverImage, _, err := api.hcloudClient.Image.GetForArchitecture(
-		ctx,
-		config.Get().ServerComponents.Ubuntu.Version,
-		config.Get().ServerComponents.Ubuntu.Architecture,
-	)
+	serverImage, err := api.getServerImage(ctx)
 	if err != nil {
-		return errors.Wrap(err, "failed to get server image")
+		return err
 	}
 
 	k8sNetwork, _, err := api.hcloudClient.Network.Get(ctx, config.Get().ClusterName)
@@ -511,11 +598,6 @@ func (api *ApplicationAPI) createServer(ctx context.Context) error { //nolint:fu
 
 		prop.PlacementGroup = placementGroupResults.PlacementGroup
 
-		// install kubelet kubeadm on server start
-		if i > 1 {
-			prop.UserData = api.getCommonInstallCommand()
-		}
-
 		serverResults, _, err := api.hcloudClient.Server.Create(ctx, prop)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create server")
@@ -603,37 +685,54 @@ func (api *ApplicationAPI) createNetwork(ctx context.Context) error {
 	return nil
 }
 
-func (api *ApplicationAPI) NewCluster(ctx context.Context) error { //nolint:cyclop
+func (api *ApplicationAPI) NewCluster(ctx context.Context) (Resources, error) { //nolint:cyclop,funlen
 	log.Info("Creating cluster...")
 
+	progress := newProgress(newClusterPhases)
+	defer progress.close()
+
+	progress.next("Creating network")
+
 	err := api.createNetwork(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error in create network")
+		return nil, errors.Wrap(err, "error in create network")
 	}
 
+	progress.next("Creating firewall")
+
 	err = api.CreateFirewall(ctx, true, true)
 	if err != nil {
-		return errors.Wrap(err, "error in create firewall")
+		return nil, errors.Wrap(err, "error in create firewall")
 	}
 
+	progress.next("Creating ssh key")
+
 	err = api.createSSHKey(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error in create sshkey")
+		return nil, errors.Wrap(err, "error in create sshkey")
 	}
 
+	progress.next("Creating load balancer")
+
 	err = api.createLoadBalancer(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error in create loadbalancer")
+		return nil, errors.Wrap(err, "error in create loadbalancer")
 	}
 
+	progress.next("Creating servers")
+
 	err = api.createServer(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error in create server")
+		return nil, errors.Wrap(err, "error in create server")
 	}
 
+	progress.next("Initializing first master node")
+
+	installResults := api.installJoiningMasters(ctx)
+
 	err = api.initFirstMasterNode(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error in init first master nodes")
+		return nil, errors.Wrap(err, "error in init first master nodes")
 	}
 
 	err = api.saveKubeconfig()
@@ -641,25 +740,89 @@ func (api *ApplicationAPI) NewCluster(ctx context.Context) error { //nolint:cycl
 		log.WithError(err).Warn("error in saving kubeconfig")
 	}
 
+	progress.next("Joining master nodes")
+
 	for i := 2; i <= config.Get().MasterCount; i++ {
 		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
 
 		log := log.WithField("server", serverName)
 
+		if err := <-installResults[serverName]; err != nil {
+			log.WithError(err).Error("error in install")
+
+			continue
+		}
+
 		err = api.joinToMasterNodes(ctx, serverName)
 		if err != nil {
 			log.WithError(err).Error(err, "error in join")
 		}
 	}
 
+	progress.next("Installing cluster components")
+
 	err = api.postInstall(ctx, false)
 	if err != nil {
-		return errors.Wrap(err, "error in postInstall")
+		return nil, errors.Wrap(err, "error in postInstall")
+	}
+
+	if timeout := *config.Get().CliArgs.CreateVerifyTimeout; timeout > 0 {
+		progress.next("Verifying cluster")
+
+		if err := api.verifyCluster(ctx, timeout); err != nil {
+			return nil, errors.Wrap(err, "error in verify cluster")
+		}
 	}
 
+	progress.done()
+
 	log.Info("Cluster created!")
 
-	return nil
+	return api.clusterResources(ctx)
+}
+
+// clusterResources returns cloud resources of cluster.
+func (api *ApplicationAPI) clusterResources(ctx context.Context) (Resources, error) {
+	resources := make(Resources, 0)
+
+	network, _, err := api.hcloudClient.Network.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting network")
+	}
+
+	if network != nil {
+		resources = append(resources, Resource{Type: "network", Name: network.Name, ID: network.ID})
+	}
+
+	loadBalancer, _, err := api.hcloudClient.LoadBalancer.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting loadbalancer")
+	}
+
+	if loadBalancer != nil {
+		resources = append(resources, Resource{
+			Type: "loadbalancer",
+			Name: loadBalancer.Name,
+			ID:   loadBalancer.ID,
+			IP:   loadBalancer.PublicNet.IPv4.IP.String(),
+		})
+	}
+
+	servers, err := api.listServers(ctx, api.getMasterLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		resources = append(resources, Resource{
+			Type: "server",
+			Name: server.Name,
+			ID:   server.ID,
+			IP:   server.PublicNet.IPv4.IP.String(),
+		})
+	}
+
+	return resources, nil
 }
 
 func (api *ApplicationAPI) DeleteCluster(ctx context.Context) {
@@ -671,22 +834,20 @@ func (api *ApplicationAPI) DeleteCluster(ctx context.Context) {
 	drainer.DeleteCluster(ctx)
 }
 
-func (api *ApplicationAPI) execCommand(ipAddress string, command string) (string, string, error) {
-	log.Debugf("user=%s,ipAddress=%s,command=%s", api.sshRootUser, ipAddress, command)
-
+func (api *ApplicationAPI) sshDial(user string, ipAddress string) (*ssh.Client, error) {
 	privateKey, err := os.ReadFile(config.Get().SSHPrivateKey)
 	if err != nil {
-		return "", "", errors.Wrap(err, "error in read private key")
+		return nil, errors.Wrap(err, "error in read private key")
 	}
 
 	key, err := ssh.ParsePrivateKey(privateKey)
 	if err != nil {
-		return "", "", errors.Wrap(err, "error parsing private key")
+		return nil, errors.Wrap(err, "error parsing private key")
 	}
 
 	config := &ssh.ClientConfig{
-		User:            api.sshRootUser,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+		User:            user,
+		HostKeyCallback: api.hostKeyCallback(ipAddress),
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(key),
 		},
@@ -694,9 +855,42 @@ func (api *ApplicationAPI) execCommand(ipAddress string, command string) (string
 
 	client, err := ssh.Dial("tcp", net.JoinHostPort(ipAddress, "22"), config)
 	if err != nil {
-		return "", "", errors.Wrap(err, "error connecting to ssh")
+		return nil, errors.Wrap(err, "error connecting to ssh")
+	}
+
+	return client, nil
+}
+
+func (api *ApplicationAPI) execCommand(ipAddress string, command string) (string, string, error) {
+	return api.execCommandAs(api.sshRootUser, ipAddress, command)
+}
+
+func (api *ApplicationAPI) execCommandAs(user string, ipAddress string, command string) (string, string, error) {
+	return api.execCommandAsContext(context.Background(), user, ipAddress, command)
+}
+
+// execCommandQuiet does not stream output, used for commands returning data.
+func (api *ApplicationAPI) execCommandQuiet(ipAddress string, command string) (string, string, error) {
+	return api.execCommandAsContext(quietContext(context.Background()), api.sshRootUser, ipAddress, command)
+}
+
+type quietContextKey struct{}
+
+func quietContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quietContextKey{}, true)
+}
+
+// execCommandAsContext closes ssh connection when context is done.
+func (api *ApplicationAPI) execCommandAsContext(ctx context.Context, user string, ipAddress string, command string) (string, string, error) { //nolint:lll
+	log.Debugf("user=%s,ipAddress=%s,command=%s", user, ipAddress, command)
+
+	client, err := api.sshDial(user, ipAddress)
+	if err != nil {
+		return "", "", err
 	}
 
+	defer client.Close()
+
 	session, err := client.NewSession()
 	if err != nil {
 		return "", "", errors.Wrap(err, "error creating session")
@@ -704,15 +898,32 @@ func (api *ApplicationAPI) execCommand(ipAddress string, command string) (string
 
 	defer session.Close()
 
+	// closing connection interrupts running command
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	sshCommand := fmt.Sprintf(`echo "%s" | base64 -d | sudo bash`, base64.StdEncoding.EncodeToString([]byte(command)))
+	if api.output != nil && ctx.Value(quietContextKey{}) == nil {
+		serverOutput := api.output.Writer(api.serverName(ipAddress))
+		defer serverOutput.Flush()
 
-	log.Debug(sshCommand)
+		session.Stdout = io.MultiWriter(&stdout, serverOutput)
+		session.Stderr = io.MultiWriter(&stderr, serverOutput)
+	}
+
+	// command is logged above, encoded command can not be redacted
+	sshCommand := fmt.Sprintf(`echo "%s" | base64 -d | sudo bash`, base64.StdEncoding.EncodeToString([]byte(command)))
 
 	err = session.Run(sshCommand)
+	if ctx.Err() != nil {
+		return stdout.String(), stderr.String(), errors.Wrap(ctx.Err(), "command interrupted")
+	}
+
 	if err != nil {
 		log.Error(stdout.String(), stderr.String())
 
@@ -722,25 +933,12 @@ func (api *ApplicationAPI) execCommand(ipAddress string, command string) (string
 	return stdout.String(), stderr.String(), nil
 }
 
-func (api *ApplicationAPI) ListConfigurations(ctx context.Context) {
-	type DatacentersType struct {
-		Location string
-		Name     string
-	}
-
-	type ResultType struct {
-		Locations   []string
-		Datacenters []DatacentersType
-		ServerType  []string
-	}
-
-	result := ResultType{}
-
-	var err error
+func (api *ApplicationAPI) ListConfigurations(ctx context.Context) (*Configurations, error) {
+	result := Configurations{}
 
 	locations, err := api.hcloudClient.Location.All(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, errors.Wrap(err, "error listing locations")
 	}
 
 	for _, location := range locations {
@@ -749,11 +947,11 @@ func (api *ApplicationAPI) ListConfigurations(ctx context.Context) {
 
 	datacenters, err := api.hcloudClient.Datacenter.All(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, errors.Wrap(err, "error listing datacenters")
 	}
 
 	for _, datacenter := range datacenters {
-		result.Datacenters = append(result.Datacenters, DatacentersType{
+		result.Datacenters = append(result.Datacenters, Datacenter{
 			Name:     datacenter.Name,
 			Location: datacenter.Location.Name,
 		})
@@ -761,30 +959,33 @@ func (api *ApplicationAPI) ListConfigurations(ctx context.Context) {
 
 	servertypes, err := api.hcloudClient.ServerType.All(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, errors.Wrap(err, "error listing server types")
 	}
 
 	for _, servertype := range servertypes {
-		result.ServerType = append(result.ServerType, servertype.Name)
+		result.ServerTypes = append(result.ServerTypes, servertype.Name)
 	}
 
-	resultYAML, err := yaml.Marshal(result)
+	return &result, nil
+}
+
+func (api *ApplicationAPI) getDeploymentValues() []byte {
+	hooks, err := config.RenderHooks()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Infof("\n%s", string(resultYAML))
-}
+	values := *config.Get()
+	values.Hooks = hooks
 
-func (api *ApplicationAPI) getDeploymentValues() string {
-	resultYAML, err := yaml.Marshal(config.Get())
+	resultYAML, err := yaml.Marshal(values)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	log.Debug(string(resultYAML))
 
-	return base64.StdEncoding.EncodeToString(resultYAML)
+	return resultYAML
 }
 
 func (api *ApplicationAPI) PatchClusterDeployment(ctx context.Context) error {
@@ -819,107 +1020,14 @@ func (api *ApplicationAPI) listServers(ctx context.Context, labelSelector string
 
 		opts.ListOpts.Page++
 
-		servers = append(servers, page...)
-	}
-
-	return servers, nil
-}
-
-func (api *ApplicationAPI) ExecuteAdHoc(ctx context.Context, user string, command string, runOnMasters bool, runOnWorkers bool, copyNewScripts bool) { //nolint:funlen,lll,cyclop
-	log.Info("Executing adhoc...")
-
-	if len(user) > 0 {
-		api.sshRootUser = user
-	}
-
-	var allServers []*hcloud.Server
-
-	if runOnWorkers {
-		log.Info("Get worker nodes...")
-
-		workerServers, err := api.listServers(ctx, nodeGroupSelector)
-		if err != nil {
-			log.WithError(err).Error()
+		for _, server := range page {
+			api.servers.Store(server.PublicNet.IPv4.IP.String(), server)
 		}
 
-		allServers = append(allServers, workerServers...)
-	}
-
-	if runOnMasters {
-		log.Info("Get master nodes...")
-
-		masterServers, err := api.listServers(ctx, api.getMasterLabels())
-		if err != nil {
-			log.WithError(err).Error()
-		}
-
-		allServers = append(allServers, masterServers...)
-	}
-
-	if len(allServers) == 0 {
-		log.Error("No servers found")
-
-		return
-	}
-
-	log.Infof("Servers found: %d", len(allServers))
-
-	adhocStatus := make(map[string]string)
-
-	var (
-		wg    sync.WaitGroup
-		mutex sync.Mutex
-	)
-
-	wg.Add(len(allServers))
-
-	log.Info("Start executing command on selected nodes")
-
-	for _, server := range allServers {
-		go func(server *hcloud.Server) {
-			defer wg.Done()
-
-			log := log.WithField("server", server.Name)
-
-			serverIP, err := server.PublicNet.IPv4.IP.MarshalText()
-			if err != nil {
-				log.WithError(err).Error("can not get server IP")
-				adhocStatus[server.Name] = err.Error()
-
-				return
-			}
-
-			if copyNewScripts {
-				if err = api.downloadNewScripts(server.Name, string(serverIP)); err != nil {
-					log.WithError(err).Fatal()
-				}
-			}
-
-			stdout, stderr, err := api.execCommand(string(serverIP), command)
-			if err != nil {
-				log.WithError(err).Error(stderr)
-				adhocStatus[server.Name] = err.Error()
-
-				return
-			}
-
-			log.Infof("stdout=%s,stderr=%s", stdout, stderr)
-
-			mutex.Lock()
-			adhocStatus[server.Name] = "ok"
-			mutex.Unlock()
-		}(server)
+		servers = append(servers, page...)
 	}
 
-	wg.Wait()
-
-	for name, status := range adhocStatus {
-		if status == "ok" {
-			log.Infof("%s -> %s", name, status)
-		} else {
-			log.Errorf("%s -> %s", name, status)
-		}
-	}
+	return servers, nil
 }
 
 func (api *ApplicationAPI) getMasterLabels() string {
@@ -939,9 +1047,23 @@ func (api *ApplicationAPI) getMasterLabels() string {
 func (api *ApplicationAPI) downloadNewScripts(serverName string, serverIP string) error {
 	log := log.WithField("server", serverName)
 
-	log.Info("Clear current root directory, loading new scripts...")
+	log.Info("Clear current root directory, uploading new scripts...")
+
+	return api.installScripts(api.sshRootUser, serverIP)
+}
+
+// installScripts uploads scripts bundle with initial config and extracts it on node.
+func (api *ApplicationAPI) installScripts(user string, serverIP string) error {
+	files := map[string][]byte{
+		"scripts.tar.gz": api.scriptsBundle.Data,
+		"values.yaml":    api.getDeploymentValues(),
+	}
+
+	if err := api.uploadFiles(user, serverIP, uploadDir, files); err != nil {
+		return errors.Wrap(err, "error uploading scripts")
+	}
 
-	_, stderr, err := api.execCommand(serverIP, api.getCommonExecCommand())
+	_, stderr, err := api.execCommandAs(user, serverIP, api.getCommonExecCommand())
 	if err != nil {
 		return errors.Wrap(err, stderr)
 	}
@@ -949,9 +1071,25 @@ func (api *ApplicationAPI) downloadNewScripts(serverName string, serverIP string
 	return nil
 }
 
-func (api *ApplicationAPI) UpgradeControlPlane(ctx context.Context) {
+func (api *ApplicationAPI) UpgradeControlPlane(ctx context.Context, skipBackup bool) {
 	log.Info("Executing controlplane upgrade...")
 
+	if skipBackup {
+		log.Warn("Skipping etcd backup before upgrade")
+	} else {
+		output := fmt.Sprintf("%s-%spre-upgrade-%s.db",
+			config.Get().ClusterName,
+			etcdSnapshotPrefix,
+			time.Now().Format("20060102150405"),
+		)
+
+		if _, err := api.BackupEtcd(ctx, output, *config.Get().CliArgs.BackupEtcdKeep); err != nil {
+			log.WithError(err).Fatal("error creating etcd backup, use -upgrade-controlplane.skip-backup to upgrade without backup") //nolint:lll
+		}
+
+		log.Infof("Pre-upgrade etcd snapshot saved to %s", output)
+	}
+
 	for i := 1; i <= config.Get().MasterCount; i++ {
 		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
 
@@ -968,7 +1106,12 @@ func (api *ApplicationAPI) UpgradeControlPlane(ctx context.Context) {
 
 		log.Info("Upgrade controlplane...")
 
-		cmd := "/root/scripts/upgrade-controlplane.sh"
+		role := config.NodeRoleJoiningMaster
+		if i == 1 {
+			role = config.NodeRoleFirstMaster
+		}
+
+		cmd := "NODE_ROLE=" + role + " /root/scripts/upgrade-controlplane.sh"
 
 		stdout, stderr, err := api.execCommand(serverIP, cmd)
 		if err != nil {
@@ -977,6 +1120,11 @@ func (api *ApplicationAPI) UpgradeControlPlane(ctx context.Context) {
 
 		log.Debugf(debugStdout, stdout)
 		log.Debugf(debugStderr, stderr)
+
+		// next master is upgraded only when current master is ready
+		if err := api.waitNodeReady(ctx, serverName); err != nil {
+			log.WithError(err).Fatal()
+		}
 	}
 
 	log.Info("Cluster upgraded!")
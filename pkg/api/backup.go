@@ -0,0 +1,274 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/s3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	etcdSnapshotCommand      = "/root/scripts/etcd-snapshot.sh"
+	etcdBackupInstallCommand = "/root/scripts/etcd-backup-install.sh"
+	etcdSnapshotPrefix       = "etcd-snapshot-"
+	etcdListSnapshotsCommand = "ls -lh /var/lib/etcd/backups/" + etcdSnapshotPrefix + "*.db"
+)
+
+type etcdSnapshot struct {
+	serverName string
+	serverIP   string
+	path       string
+	checksum   string
+}
+
+// BackupEtcd creates etcd snapshot on healthy master node, downloads it to output file
+// and uploads it to backup storage if configured.
+func (api *ApplicationAPI) BackupEtcd(ctx context.Context, output string, keep int) (*EtcdBackup, error) { //nolint:funlen,cyclop
+	log.Info("Creating etcd backup...")
+
+	if !config.Get().Backup.Enabled() && len(output) == 0 {
+		return nil, errBackupNotEnabled
+	}
+
+	if keep < 1 {
+		return nil, errInvalidBackupKeep
+	}
+
+	snapshot, err := api.createEtcdSnapshot(ctx, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	// download to same directory as output, to rename it after checksum verification
+	tempDir := ""
+	if len(output) > 0 {
+		tempDir = filepath.Dir(output)
+	}
+
+	snapshotFile, err := os.CreateTemp(tempDir, "."+etcdSnapshotPrefix+"*.db")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating temporary file")
+	}
+
+	defer os.Remove(snapshotFile.Name())
+	defer snapshotFile.Close()
+
+	log.Infof("Downloading snapshot %s from %s...", snapshot.path, snapshot.serverName)
+
+	checksum, err := api.downloadFile(api.sshRootUser, snapshot.serverIP, snapshot.path, snapshotFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != snapshot.checksum {
+		return nil, errors.Wrapf(errChecksumMismatch, "node=%s, downloaded=%s", snapshot.checksum, checksum)
+	}
+
+	size, err := snapshotFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting snapshot size")
+	}
+
+	result := EtcdBackup{
+		Server:   snapshot.serverName,
+		Snapshot: snapshot.path,
+		Output:   output,
+		Size:     size,
+		SHA256:   checksum,
+	}
+
+	if len(output) > 0 {
+		if err := snapshotFile.Sync(); err != nil {
+			return nil, errors.Wrap(err, "error writing snapshot")
+		}
+
+		if err := os.Rename(snapshotFile.Name(), output); err != nil {
+			return nil, errors.Wrap(err, "error saving snapshot")
+		}
+
+		log.Infof("Snapshot saved to %s size=%d sha256=%s", output, size, checksum)
+	}
+
+	if config.Get().Backup.Enabled() {
+		if _, err := snapshotFile.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap(err, "error reading snapshot")
+		}
+
+		result.StorageKey, err = uploadEtcdSnapshot(ctx, snapshotFile, size, path.Base(snapshot.path))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := api.listEtcdSnapshots(snapshot); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Backup created! sha256=%s", checksum)
+
+	return &result, nil
+}
+
+func uploadEtcdSnapshot(ctx context.Context, snapshotFile io.Reader, size int64, name string) (string, error) {
+	storage, err := newBackupStorage()
+	if err != nil {
+		return "", err
+	}
+
+	key := path.Join(config.Get().Backup.Prefix, name)
+
+	log.Infof("Uploading snapshot to %s/%s...", config.Get().Backup.Bucket, key)
+
+	if err := storage.PutObject(ctx, key, snapshotFile, size); err != nil {
+		return "", errors.Wrap(err, "error uploading snapshot")
+	}
+
+	return key, pruneEtcdSnapshots(ctx, storage)
+}
+
+// createEtcdSnapshot creates snapshot on first healthy master, keep is number of snapshots to keep on node.
+func (api *ApplicationAPI) createEtcdSnapshot(ctx context.Context, keep int) (*etcdSnapshot, error) {
+	for i := 1; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		log := log.WithField("master", serverName)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			log.WithError(err).Warn("master node is not available")
+
+			continue
+		}
+
+		// scripts are not redeployed on running master, they are updated by patch-cluster
+		if _, _, err = api.execCommandQuiet(serverIP, "test -x "+etcdSnapshotCommand); err != nil {
+			log.WithError(err).Warnf("%s not found, run patch-cluster action to update scripts", etcdSnapshotCommand)
+
+			continue
+		}
+
+		log.Info("Creating etcd snapshot...")
+
+		stdout, stderr, err := api.execCommand(serverIP, fmt.Sprintf("BACKUP_KEEP=%d %s", keep, etcdSnapshotCommand))
+		if err != nil {
+			log.WithError(err).Warn(stderr)
+
+			continue
+		}
+
+		log.Debugf(debugStderr, stderr)
+
+		// script prints sha256sum output in last line
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+
+		fields := strings.Fields(lines[len(lines)-1])
+		if len(fields) != 2 { //nolint:mnd
+			return nil, errors.Errorf("unexpected snapshot output %s", stdout)
+		}
+
+		return &etcdSnapshot{
+			serverName: serverName,
+			serverIP:   serverIP,
+			checksum:   fields[0],
+			path:       fields[1],
+		}, nil
+	}
+
+	return nil, errNoHealthyMaster
+}
+
+// listEtcdSnapshots logs snapshots that are kept on master node.
+func (api *ApplicationAPI) listEtcdSnapshots(snapshot *etcdSnapshot) error {
+	stdout, stderr, err := api.execCommandQuiet(snapshot.serverIP, etcdListSnapshotsCommand)
+	if err != nil {
+		return errors.Wrapf(err, "error listing snapshots on %s: %s", snapshot.serverName, stderr)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		log.WithField("master", snapshot.serverName).Infof("Snapshot on node %s", line)
+	}
+
+	return nil
+}
+
+// installEtcdBackup installs timer for scheduled etcd snapshots shipping on all master nodes,
+// only kube-controller-manager leader uploads snapshots.
+func (api *ApplicationAPI) installEtcdBackup(ctx context.Context, copyNewScripts bool) error {
+	log.Info("Installing scheduled etcd backup...")
+
+	for i := 1; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			return errors.Wrapf(err, "error waiting %s", serverName)
+		}
+
+		if copyNewScripts {
+			if err = api.downloadNewScripts(serverName, serverIP); err != nil {
+				return errors.Wrapf(err, "error uploading scripts to %s", serverName)
+			}
+		}
+
+		stdout, stderr, err := api.execCommand(serverIP, etcdBackupInstallCommand)
+		if err != nil {
+			return errors.Wrapf(err, "error installing etcd backup on %s: %s", serverName, stderr)
+		}
+
+		log.Debugf(debugStdout, stdout)
+		log.Debugf(debugStderr, stderr)
+	}
+
+	return nil
+}
+
+// pruneEtcdSnapshots removes oldest snapshots from storage, snapshot names contain date.
+func pruneEtcdSnapshots(ctx context.Context, storage *s3.Client) error {
+	objects, err := storage.ListObjects(ctx, path.Join(config.Get().Backup.Prefix, etcdSnapshotPrefix))
+	if err != nil {
+		return errors.Wrap(err, "error listing snapshots")
+	}
+
+	for len(objects) > config.Get().Backup.Retention {
+		log.Infof("Deleting old snapshot %s", objects[0].Key)
+
+		if err := storage.DeleteObject(ctx, objects[0].Key); err != nil {
+			return errors.Wrapf(err, "error deleting %s", objects[0].Key)
+		}
+
+		objects = objects[1:]
+	}
+
+	return nil
+}
+
+func newBackupStorage() (*s3.Client, error) {
+	backup := config.Get().Backup
+
+	storage, err := s3.New(backup.Endpoint, backup.Region, backup.Bucket, backup.AccessKey, backup.SecretKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating backup storage client")
+	}
+
+	return storage, nil
+}
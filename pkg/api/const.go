@@ -12,10 +12,7 @@ limitations under the License.
 */
 package api
 
-import (
-	"io/fs"
-	"time"
-)
+import "io/fs"
 
 const commonExecCommand = `#!/bin/bash
 set -ex
@@ -24,26 +21,33 @@ export DEBIAN_FRONTEND=noninteractive
 
 cd /root
 rm -rf *
-curl -sSL -o scripts.tar.gz \
-%s
 
-tar -xvf scripts.tar.gz
-mv ./%s/scripts ./scripts
+# scripts bundle and initial config are uploaded with ssh
+mv %[1]s/* /root/
+rm -rf %[1]s
+
+# verify scripts bundle
+echo "%[2]s  scripts.tar.gz" | sha256sum -c
+
+mkdir -p /root/scripts
+tar -xzf scripts.tar.gz -C /root/scripts
+rm scripts.tar.gz
 
 # make scripts executables
 chmod +x /root/scripts/*.sh
+`
 
-# initial config
-echo "%s" | base64 -d > /root/values.yaml
+// scripts that are rendered with cluster config when bundle is created.
+//
+//nolint:gochecknoglobals
+var templatedScripts = []string{"common-install.sh", "etcd-backup.sh", "etcd-backup.timer"}
 
-# prepare scripts
-/root/scripts/prepare-scripts.sh
-`
+const commonInstallCommand = "NODE_ROLE=%s /root/scripts/common-install.sh"
+
+// directory on node for uploaded files.
+const uploadDir = "/root/.hcloud-k8s-ctl"
 
-const kubeconfigFileMode = fs.FileMode(0o600)
+// run sftp server with sudo to upload files to root directories.
+const sftpServerCommand = "sudo /usr/lib/openssh/sftp-server"
 
-const (
-	hcloudLoadBalancerInterval = 15 * time.Second
-	hcloudLoadBalancerTimeout  = 10 * time.Second
-	hcloudLoadBalancerRetries  = 3
-)
+const uploadFileMode = fs.FileMode(0o600)
@@ -0,0 +1,264 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/fanout"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// prefix of path on node in copy source or destination
+	remotePathPrefix = ":"
+	// mode of downloaded files
+	downloadFileMode = 0o600
+	downloadDirMode  = 0o700
+)
+
+type CopyOptions struct {
+	// local path or :/path/on/node
+	Source string
+	// local directory or :/path/on/node
+	Destination string
+	// node-group:<name>, name:<regex> or hcloud label selector, all nodes if empty
+	Selector string
+	// maximum number of servers copying at same time, 0 is unlimited
+	Parallel int
+	// octal mode of uploaded file
+	Mode string
+}
+
+// Copy uploads local file to nodes or downloads file from nodes to <destination>/<server>/<name>,
+// checksum of file on node is verified after transfer.
+func (api *ApplicationAPI) Copy(ctx context.Context, opts CopyOptions) (CopyResults, error) { //nolint:funlen,cyclop
+	download := strings.HasPrefix(opts.Source, remotePathPrefix)
+	upload := strings.HasPrefix(opts.Destination, remotePathPrefix)
+
+	if download == upload {
+		return nil, errors.Wrap(errInvalidCopyPath, "only source or destination must start with "+remotePathPrefix)
+	}
+
+	remotePath := strings.TrimPrefix(opts.Source, remotePathPrefix)
+	if upload {
+		remotePath = strings.TrimPrefix(opts.Destination, remotePathPrefix)
+	}
+
+	if !path.IsAbs(remotePath) {
+		return nil, errors.Wrap(errInvalidCopyPath, "path on node must be absolute")
+	}
+
+	mode, err := strconv.ParseUint(opts.Mode, 8, 32)
+	if err != nil {
+		return nil, errors.Wrap(errInvalidCopyMode, opts.Mode)
+	}
+
+	servers, err := api.selectAdhocServers(ctx, AdhocOptions{
+		RunOnMasters: true,
+		RunOnWorkers: true,
+		Selector:     opts.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(servers) == 0 {
+		return nil, errNoServersFound
+	}
+
+	var checksum string
+
+	if upload {
+		if checksum, err = localChecksum(opts.Source); err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := make([]fanout.Task, 0, len(servers))
+	serverIPs := make(map[string]string, len(servers))
+
+	for _, server := range servers {
+		serverIP := server.PublicNet.IPv4.IP.String()
+		serverIPs[server.Name] = serverIP
+
+		tasks = append(tasks, fanout.Task{
+			Name: server.Name,
+			Run: func(ctx context.Context) (string, string, error) {
+				if upload {
+					return "", "", api.uploadCopy(ctx, serverIP, opts.Source, remotePath, fs.FileMode(mode), checksum)
+				}
+
+				downloaded, err := api.downloadCopy(ctx, serverIP, remotePath, copyDestination(opts.Destination, server.Name, remotePath)) //nolint:lll
+
+				return downloaded, "", err
+			},
+		})
+	}
+
+	log.Infof("Copying %s to %s on %d servers...", opts.Source, opts.Destination, len(servers))
+
+	fanoutResults := fanout.Run(ctx, tasks, fanout.Options{Parallel: opts.Parallel})
+
+	results := make(CopyResults, 0, len(fanoutResults))
+
+	for _, fanoutResult := range fanoutResults {
+		result := CopyResult{
+			Server:      fanoutResult.Name,
+			IP:          serverIPs[fanoutResult.Name],
+			Status:      fanoutResult.Status,
+			Source:      opts.Source,
+			Destination: opts.Destination,
+			SHA256:      checksum,
+			Duration:    fanoutResult.Duration.Round(time.Millisecond).String(),
+		}
+
+		localPath := opts.Source
+
+		if download {
+			result.Destination = copyDestination(opts.Destination, fanoutResult.Name, remotePath)
+			result.SHA256 = fanoutResult.Stdout
+			localPath = result.Destination
+		}
+
+		if fanoutResult.Err != nil {
+			result.Error = fanoutResult.Err.Error()
+
+			log.WithError(fanoutResult.Err).Errorf("%s -> %s", result.Server, result.Status)
+		} else if info, err := os.Stat(localPath); err == nil {
+			result.Size = info.Size()
+
+			log.Infof("%s -> %s %s", result.Server, result.Status, result.Destination)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// uploadCopy uploads local file to node and verifies checksum of uploaded file.
+func (api *ApplicationAPI) uploadCopy(ctx context.Context, serverIP, source, filePath string, mode fs.FileMode, checksum string) error { //nolint:lll
+	file, err := os.Open(source)
+	if err != nil {
+		return errors.Wrap(err, "error opening file")
+	}
+
+	defer file.Close()
+
+	client, err := api.sshDial(api.sshRootUser, serverIP)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	// closing connection interrupts transfer
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return err
+	}
+
+	defer sftpClient.Close()
+
+	if err := sftpClient.MkdirAll(path.Dir(filePath)); err != nil {
+		return errors.Wrapf(err, "error creating directory %s", path.Dir(filePath))
+	}
+
+	if err := writeFile(sftpClient, filePath, file, mode); err != nil {
+		return err
+	}
+
+	remoteChecksum, err := api.remoteChecksum(ctx, serverIP, filePath)
+	if err != nil {
+		return err
+	}
+
+	if remoteChecksum != checksum {
+		return errors.Wrapf(errChecksumMismatch, "%s on node %s, local %s", filePath, remoteChecksum, checksum)
+	}
+
+	return nil
+}
+
+// downloadCopy downloads file from node to local path, returns checksum of file.
+func (api *ApplicationAPI) downloadCopy(ctx context.Context, serverIP, filePath, localPath string) (string, error) {
+	remoteChecksum, err := api.remoteChecksum(ctx, serverIP, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), downloadDirMode); err != nil {
+		return "", errors.Wrap(err, "error creating directory")
+	}
+
+	file, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, downloadFileMode)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating file")
+	}
+
+	defer file.Close()
+
+	checksum, err := api.downloadFile(api.sshRootUser, serverIP, filePath, file)
+	if err != nil {
+		return "", err
+	}
+
+	if checksum != remoteChecksum {
+		return "", errors.Wrapf(errChecksumMismatch, "%s on node %s, downloaded %s", filePath, remoteChecksum, checksum)
+	}
+
+	return checksum, nil
+}
+
+// remoteChecksum returns sha256 checksum of file on node.
+func (api *ApplicationAPI) remoteChecksum(ctx context.Context, serverIP, filePath string) (string, error) {
+	stdout, stderr, err := api.execCommandAsContext(quietContext(ctx), api.sshRootUser, serverIP, "sha256sum -- "+utils.ShellQuote(filePath)) //nolint:lll
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting checksum of %s: %s", filePath, stderr)
+	}
+
+	checksum, _, _ := strings.Cut(strings.TrimSpace(stdout), " ")
+
+	return checksum, nil
+}
+
+func localChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrap(err, "error opening file")
+	}
+
+	defer file.Close()
+
+	return fileChecksum(file)
+}
+
+// copyDestination returns local path of file downloaded from server.
+func copyDestination(destination, serverName, filePath string) string {
+	return filepath.Join(destination, serverName, path.Base(filePath))
+}
@@ -0,0 +1,190 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"sort"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/pricing"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CostGroupControlPlane   = "control-plane"
+	CostGroupCurrentWorkers = "current-workers"
+	CostGroupMaxWorkers     = "max-workers"
+
+	costResourceServer       = "server"
+	costResourceLoadBalancer = "loadbalancer"
+)
+
+type EstimateCostOptions struct {
+	// pricing snapshot file, prices are loaded from Hetzner Cloud API if empty
+	Pricing string
+	// save prices loaded from Hetzner Cloud API to snapshot file
+	SavePricing string
+}
+
+// NewOfflineApplicationAPI creates api without Hetzner Cloud client, used to estimate cost from pricing snapshot.
+func NewOfflineApplicationAPI() *ApplicationAPI {
+	return &ApplicationAPI{}
+}
+
+// EstimateCost returns monthly cost of control plane, current workers
+// and worst case when all cluster-autoscaler groups have maxSize nodes.
+func (api *ApplicationAPI) EstimateCost(ctx context.Context, opts EstimateCostOptions) (*CostEstimate, error) {
+	prices, err := api.getPricing(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := CostEstimate{
+		Currency:  prices.Currency,
+		PricingAt: prices.CreatedAt.Format("2006-01-02"),
+		Items:     make([]CostItem, 0),
+	}
+
+	masterServers := config.Get().MasterServers
+	masterLoadBalancer := config.Get().MasterLoadBalancer
+
+	masterPrice, err := prices.ServerType(masterServers.ServerType, config.Get().Location)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting master servers price")
+	}
+
+	loadBalancerPrice, err := prices.LoadBalancerType(masterLoadBalancer.LoadBalancerType, config.Get().Location)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting load balancer price")
+	}
+
+	estimate.add(CostGroupControlPlane, costResourceServer, masterServers.ServerType, config.Get().Location, config.Get().MasterCount, masterPrice) //nolint:lll
+	estimate.add(CostGroupControlPlane, costResourceLoadBalancer, masterLoadBalancer.LoadBalancerType, config.Get().Location, 1, loadBalancerPrice) //nolint:lll
+
+	if err := api.estimateCurrentWorkers(ctx, prices, &estimate); err != nil {
+		return nil, err
+	}
+
+	groups, err := config.Get().AutoscalingGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		price, err := prices.ServerType(group.InstanceType, group.Region)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting autoscaling group %s price", group.Name)
+		}
+
+		estimate.add(CostGroupMaxWorkers, costResourceServer, group.InstanceType, group.Region, group.MaxSize, price)
+	}
+
+	estimate.ControlPlane = estimate.total(CostGroupControlPlane)
+	estimate.Current = estimate.ControlPlane.Add(estimate.total(CostGroupCurrentWorkers), 1)
+	estimate.WorstCase = estimate.ControlPlane.Add(estimate.total(CostGroupMaxWorkers), 1)
+
+	log.Infof("Monthly cost (%s, net): control plane %.2f, current %.2f, worst case %.2f",
+		estimate.Currency,
+		estimate.ControlPlane.Net,
+		estimate.Current.Net,
+		estimate.WorstCase.Net,
+	)
+
+	return &estimate, nil
+}
+
+// getPricing returns prices from snapshot file or Hetzner Cloud API.
+func (api *ApplicationAPI) getPricing(ctx context.Context, opts EstimateCostOptions) (*pricing.Snapshot, error) {
+	if len(opts.Pricing) > 0 {
+		log.Infof("Using pricing snapshot %s", opts.Pricing)
+
+		return pricing.Load(opts.Pricing)
+	}
+
+	if api.hcloudClient == nil {
+		return nil, errPricingNotSet
+	}
+
+	serverTypes, err := api.hcloudClient.ServerType.All(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing server types")
+	}
+
+	loadBalancerTypes, err := api.hcloudClient.LoadBalancerType.All(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing load balancer types")
+	}
+
+	prices, err := pricing.FromHcloud(serverTypes, loadBalancerTypes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing prices")
+	}
+
+	if len(opts.SavePricing) > 0 {
+		log.Infof("Saving pricing snapshot to %s", opts.SavePricing)
+
+		if err := prices.Save(opts.SavePricing); err != nil {
+			return nil, err
+		}
+	}
+
+	return prices, nil
+}
+
+// estimateCurrentWorkers adds cost of servers created by cluster-autoscaler.
+func (api *ApplicationAPI) estimateCurrentWorkers(ctx context.Context, prices *pricing.Snapshot, estimate *CostEstimate) error { //nolint:lll
+	if api.hcloudClient == nil {
+		log.Warn("Hetzner Cloud API is not available, current workers are not included in estimate")
+
+		return nil
+	}
+
+	servers, err := api.listServers(ctx, nodeGroupSelector)
+	if err != nil {
+		return err
+	}
+
+	type workerType struct {
+		serverType string
+		location   string
+	}
+
+	workers := make(map[workerType]int)
+
+	for _, server := range servers {
+		workers[workerType{server.ServerType.Name, server.Datacenter.Location.Name}]++
+	}
+
+	types := make([]workerType, 0, len(workers))
+
+	for worker := range workers {
+		types = append(types, worker)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].serverType+types[i].location < types[j].serverType+types[j].location
+	})
+
+	for _, worker := range types {
+		price, err := prices.ServerType(worker.serverType, worker.location)
+		if err != nil {
+			return errors.Wrap(err, "error getting workers price")
+		}
+
+		estimate.add(CostGroupCurrentWorkers, costResourceServer, worker.serverType, worker.location, workers[worker], price)
+	}
+
+	return nil
+}
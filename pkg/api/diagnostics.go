@@ -0,0 +1,233 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/fanout"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const diagnosticsFileMode = 0o600
+
+type diagnosticsCommand struct {
+	name    string
+	command string
+}
+
+// diagnosticsCommands returns commands collected from each node, journal logs are limited by since.
+func diagnosticsCommands(since time.Duration) []diagnosticsCommand {
+	journalSince := fmt.Sprintf("--since=-%ds", int(since.Seconds()))
+
+	return []diagnosticsCommand{
+		{"system.txt", "hostnamectl; uname -a; uptime; free -m; df -h"},
+		{"failed-units.txt", "systemctl list-units --failed --no-pager"},
+		{"kubelet.log", "journalctl -u kubelet --no-pager " + journalSince},
+		{"containerd.log", "journalctl -u containerd --no-pager " + journalSince},
+		{"dmesg.txt", "dmesg -T"},
+		{"crictl-ps.txt", "crictl ps -a"},
+		{"crictl-pods.txt", "crictl pods"},
+		{"manifests.txt", "tail -n +1 /etc/kubernetes/manifests/*.yaml"},
+		{"kubelet-config.yaml", "cat /var/lib/kubelet/config.yaml"},
+		{"network.txt", "ip addr; ip route"},
+	}
+}
+
+type DiagnosticsOptions struct {
+	// node-group:<name>, name:<regex> or hcloud label selector, all nodes if empty
+	Selector string
+	// path of tar.gz archive, default diagnostics-<clusterName>-<timestamp>.tar.gz
+	Output string
+	// period of journal logs
+	Since time.Duration
+}
+
+// diagnosticsArchive writes files from many servers to tar.gz archive.
+type diagnosticsArchive struct {
+	mutex     sync.Mutex
+	prefix    string
+	file      *os.File
+	gzWriter  *gzip.Writer
+	tarWriter *tar.Writer
+}
+
+func newDiagnosticsArchive(filePath string) (*diagnosticsArchive, error) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, diagnosticsFileMode)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating archive")
+	}
+
+	gzWriter := gzip.NewWriter(file)
+
+	return &diagnosticsArchive{
+		prefix:    strings.TrimSuffix(path.Base(filePath), ".tar.gz"),
+		file:      file,
+		gzWriter:  gzWriter,
+		tarWriter: tar.NewWriter(gzWriter),
+	}, nil
+}
+
+// add adds redacted file of server to archive.
+func (a *diagnosticsArchive) add(server, name, content string) error {
+	content = redact.String(content)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	header := &tar.Header{
+		Name:    path.Join(a.prefix, server, name),
+		Mode:    diagnosticsFileMode,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}
+
+	if err := a.tarWriter.WriteHeader(header); err != nil {
+		return errors.Wrap(err, "error writing archive header")
+	}
+
+	if _, err := a.tarWriter.Write([]byte(content)); err != nil {
+		return errors.Wrap(err, "error writing archive")
+	}
+
+	return nil
+}
+
+func (a *diagnosticsArchive) Close() error {
+	tarErr := a.tarWriter.Close()
+	gzErr := a.gzWriter.Close()
+	fileErr := a.file.Close()
+
+	for _, err := range []error{tarErr, gzErr, fileErr} {
+		if err != nil {
+			return errors.Wrap(err, "error closing archive")
+		}
+	}
+
+	return nil
+}
+
+// CollectDiagnostics collects logs and state of selected nodes to local tar.gz archive, secrets are redacted.
+func (api *ApplicationAPI) CollectDiagnostics(ctx context.Context, opts DiagnosticsOptions) (*Diagnostics, error) { //nolint:funlen,lll
+	servers, err := api.selectAdhocServers(ctx, AdhocOptions{
+		RunOnMasters: true,
+		RunOnWorkers: true,
+		Selector:     opts.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(servers) == 0 {
+		return nil, errNoServersFound
+	}
+
+	output := opts.Output
+	if len(output) == 0 {
+		output = fmt.Sprintf("diagnostics-%s-%s.tar.gz", config.Get().ClusterName, time.Now().Format("20060102150405"))
+	}
+
+	archive, err := newDiagnosticsArchive(output)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := diagnosticsCommands(opts.Since)
+	tasks := make([]fanout.Task, 0, len(servers))
+
+	for _, server := range servers {
+		serverIP := server.PublicNet.IPv4.IP.String()
+
+		tasks = append(tasks, fanout.Task{
+			Name: server.Name,
+			Run: func(ctx context.Context) (string, string, error) {
+				return "", "", api.collectServerDiagnostics(ctx, archive, server.Name, serverIP, commands)
+			},
+		})
+	}
+
+	log.Infof("Collecting diagnostics from %d servers...", len(servers))
+
+	fanoutResults := fanout.Run(ctx, tasks, fanout.Options{})
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	result := Diagnostics{
+		Archive: output,
+		Servers: make([]DiagnosticsServer, 0, len(fanoutResults)),
+	}
+
+	for _, fanoutResult := range fanoutResults {
+		server := DiagnosticsServer{
+			Server:   fanoutResult.Name,
+			Status:   fanoutResult.Status,
+			Duration: fanoutResult.Duration.Round(time.Millisecond).String(),
+		}
+
+		if fanoutResult.Err != nil {
+			server.Error = fanoutResult.Err.Error()
+
+			log.WithError(fanoutResult.Err).Errorf("%s -> %s", server.Server, server.Status)
+		}
+
+		result.Servers = append(result.Servers, server)
+	}
+
+	log.Infof("Diagnostics saved to %s", output)
+
+	return &result, nil
+}
+
+// collectServerDiagnostics runs diagnostics commands on server, output of failed command is saved with error,
+// error is returned only if command was not executed.
+func (api *ApplicationAPI) collectServerDiagnostics(ctx context.Context, archive *diagnosticsArchive, serverName, serverIP string, commands []diagnosticsCommand) error { //nolint:lll
+	for _, command := range commands {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "context error")
+		}
+
+		stdout, stderr, err := api.execCommandAsContext(quietContext(ctx), api.sshRootUser, serverIP, command.command)
+
+		content := fmt.Sprintf("# %s\n%s%s", command.command, stdout, stderr)
+
+		var exitError *ssh.ExitError
+
+		switch {
+		case err == nil:
+		case errors.As(err, &exitError):
+			content += fmt.Sprintf("\n# error: %s\n", err)
+		default:
+			return errors.Wrapf(err, "error collecting %s", command.name)
+		}
+
+		if err := archive.add(serverName, command.name, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -18,4 +18,21 @@ var (
 	errRetryLimitReached  = errors.New("retry limit reached")
 	errLocationNotFound   = errors.New("location not found")
 	errDatacenterNotFound = errors.New("datacenter not found")
+	errBackupNotEnabled   = errors.New("backup storage is not configured and output is not set")
+	errNoHealthyMaster    = errors.New("no healthy master node")
+	errNoSnapshots        = errors.New("no snapshots in backup storage")
+	errChecksumMismatch   = errors.New("checksum mismatch")
+	errInvalidBackupKeep  = errors.New("at least one snapshot must be kept on node")
+	errJoinTokenNotFound  = errors.New("join command has no token")
+	errNoServersFound     = errors.New("no servers found")
+	errHostKeyMismatch    = errors.New("host key mismatch")
+	errNodeNotSet         = errors.New("node is not set")
+	errInvalidForward     = errors.New("invalid port forward, must be localPort:remoteHost:remotePort")
+	errInvalidCopyPath    = errors.New("invalid copy path")
+	errInvalidCopyMode    = errors.New("invalid file mode")
+	errClusterNotHealthy  = errors.New("cluster is not healthy")
+	errPricingNotSet      = errors.New("pricing snapshot is required without hetzner token")
+
+	errIssueKubeconfigUserNotSet = errors.New("user is not set")
+	errIssueKubeconfigTTL        = errors.New("invalid ttl")
 )
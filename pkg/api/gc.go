@@ -0,0 +1,176 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kube"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GCKindServer = "server"
+	GCKindNode   = "node"
+
+	GCStatusOrphan  = "orphan"
+	GCStatusDeleted = "deleted"
+	GCStatusFailed  = "failed"
+)
+
+type GCOptions struct {
+	// servers created less than MinAge ago may still be joining cluster
+	MinAge time.Duration
+	// delete orphan servers and nodes, only report if false
+	Delete bool
+}
+
+// GC finds autoscaler servers that are not registered as kubernetes nodes
+// and kubernetes nodes which server no longer exists.
+func (api *ApplicationAPI) GC(ctx context.Context, opts GCOptions) (GCResults, error) { //nolint:cyclop
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := kubeClient.Nodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting nodes")
+	}
+
+	servers, err := api.listServers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make(map[int64]bool, len(nodes))
+	nodeNames := make(map[string]bool, len(nodes))
+
+	for _, node := range nodes {
+		if serverID, ok := kube.ServerID(node.ProviderID); ok {
+			nodeIDs[serverID] = true
+		}
+
+		nodeNames[node.Name] = true
+	}
+
+	serverIDs := make(map[int64]bool, len(servers))
+	serverNames := make(map[string]bool, len(servers))
+
+	for _, server := range servers {
+		serverIDs[server.ID] = true
+		serverNames[server.Name] = true
+	}
+
+	results := make(GCResults, 0)
+
+	for _, server := range servers {
+		nodeGroup, ok := server.Labels[nodeGroupSelector]
+		if !ok || nodeIDs[server.ID] || nodeNames[server.Name] {
+			continue
+		}
+
+		age := time.Since(server.Created)
+		if age < opts.MinAge {
+			log.Infof("Server %s is not registered in kubernetes, created %s ago", server.Name, age.Round(time.Second))
+
+			continue
+		}
+
+		result := GCResult{
+			Kind:      GCKindServer,
+			Name:      server.Name,
+			ServerID:  strconv.FormatInt(server.ID, 10),
+			NodeGroup: nodeGroup,
+			Age:       age.Round(time.Minute).String(),
+			Status:    GCStatusOrphan,
+		}
+
+		if opts.Delete {
+			result.setDeleteError(api.deleteServer(ctx, server))
+		}
+
+		results = append(results, result)
+	}
+
+	for _, node := range nodes {
+		serverID, ok := kube.ServerID(node.ProviderID)
+
+		// node without providerID is not initialized by cloud controller manager yet
+		if (ok && serverIDs[serverID]) || (!ok && serverNames[node.Name]) {
+			continue
+		}
+
+		result := GCResult{
+			Kind:   GCKindNode,
+			Name:   node.Name,
+			Age:    node.Age,
+			Status: GCStatusOrphan,
+		}
+
+		if ok {
+			result.ServerID = strconv.FormatInt(serverID, 10)
+		}
+
+		if opts.Delete {
+			log.Infof("Deleting node %s...", node.Name)
+
+			result.setDeleteError(kubeClient.DeleteNode(ctx, node.Name))
+		}
+
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Kind+"/"+results[i].Name < results[j].Kind+"/"+results[j].Name
+	})
+
+	for _, result := range results {
+		log.Warnf("Orphan %s %s (server id %s, age %s) is %s", result.Kind, result.Name, result.ServerID, result.Age, result.Status) //nolint:lll
+	}
+
+	return results, nil
+}
+
+func (api *ApplicationAPI) deleteServer(ctx context.Context, server *hcloud.Server) error {
+	log.Infof("Deleting server %s...", server.Name)
+
+	result, _, err := api.hcloudClient.Server.DeleteWithResult(ctx, server)
+	if err != nil {
+		return errors.Wrapf(err, "error deleting server %s", server.Name)
+	}
+
+	if err := api.hcloudClient.Action.WaitFor(ctx, result.Action); err != nil {
+		return errors.Wrapf(err, "error waiting server %s deleted", server.Name)
+	}
+
+	return nil
+}
+
+func (r *GCResult) setDeleteError(err error) {
+	if err != nil {
+		log.WithError(err).Errorf("error deleting %s %s", r.Kind, r.Name)
+
+		r.Status = GCStatusFailed
+		r.Error = err.Error()
+
+		return
+	}
+
+	r.Status = GCStatusDeleted
+}
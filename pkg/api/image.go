@@ -0,0 +1,303 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	imageBuilderSuffix = "-image-builder"
+	imageLabelPrefix   = "hcloud-k8s-ctl/"
+	labelValueMaxSize  = 63
+)
+
+// commands to remove server specific data before creating snapshot.
+const imageCleanupCommand = `
+set -ex
+
+systemctl stop kubelet
+rm -f /etc/kubernetes/kubelet/config.yaml
+
+# remove scripts and values.yaml with secrets
+rm -rf /root/*
+
+cloud-init clean --logs
+journalctl --rotate
+journalctl --vacuum-time=1s
+`
+
+var labelValueRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// getServerImage returns snapshot from config if exists, or official ubuntu image.
+func (api *ApplicationAPI) getServerImage(ctx context.Context) (*hcloud.Image, error) {
+	if snapshotID := config.Get().ServerComponents.Ubuntu.SnapshotID; snapshotID > 0 {
+		image, _, err := api.hcloudClient.Image.GetByID(ctx, snapshotID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get snapshot")
+		}
+
+		if image == nil {
+			return nil, errors.Errorf("snapshot %d not found", snapshotID)
+		}
+
+		return image, nil
+	}
+
+	image, _, err := api.hcloudClient.Image.GetForArchitecture(
+		ctx,
+		config.Get().ServerComponents.Ubuntu.Version,
+		config.Get().ServerComponents.Ubuntu.Architecture,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get server image")
+	}
+
+	return image, nil
+}
+
+// getBootstrapUser returns ssh user for fresh server,
+// snapshot already have disabled root login after common install.
+func (api *ApplicationAPI) getBootstrapUser() string {
+	if config.Get().ServerComponents.Ubuntu.SnapshotID > 0 {
+		return config.Get().ServerComponents.Ubuntu.UserName
+	}
+
+	// hetzner cloud default user is root
+	return "root"
+}
+
+// BuildImage boots temporary server, runs common install and creates snapshot.
+func (api *ApplicationAPI) BuildImage(ctx context.Context) (int64, error) { //nolint:funlen,cyclop
+	log.Info("Building image...")
+
+	builderName := config.Get().ClusterName + imageBuilderSuffix
+
+	serverType, _, err := api.hcloudClient.ServerType.Get(ctx, config.Get().MasterServers.ServerType)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get server type")
+	}
+
+	if serverType == nil {
+		return 0, errors.Errorf("server type %s not found", config.Get().MasterServers.ServerType)
+	}
+
+	// always build from official image
+	serverImage, _, err := api.hcloudClient.Image.GetForArchitecture(
+		ctx,
+		config.Get().ServerComponents.Ubuntu.Version,
+		config.Get().ServerComponents.Ubuntu.Architecture,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get server image")
+	}
+
+	location, _, err := api.hcloudClient.Location.Get(ctx, config.Get().Location)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get location")
+	}
+
+	sshKey, err := api.getImageBuilderSSHKey(ctx, builderName)
+	if err != nil {
+		return 0, err
+	}
+
+	serverResults, _, err := api.hcloudClient.Server.Create(ctx, hcloud.ServerCreateOpts{
+		Name:             builderName,
+		ServerType:       serverType,
+		Image:            serverImage,
+		Location:         location,
+		SSHKeys:          []*hcloud.SSHKey{sshKey},
+		StartAfterCreate: hcloud.Ptr(true),
+		Labels: map[string]string{
+			imageLabelPrefix + "image-builder": config.Get().ClusterName,
+		},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create server")
+	}
+
+	defer func() {
+		log.Info("Deleting temporary server...")
+
+		// use new context, current context can be canceled
+		if _, _, err := api.hcloudClient.Server.DeleteWithResult(context.Background(), serverResults.Server); err != nil { //nolint:contextcheck,lll
+			log.WithError(err).Errorf("failed to delete server %s, delete it manually", builderName)
+		}
+
+		if sshKey.Name == builderName {
+			if _, err := api.hcloudClient.SSHKey.Delete(context.Background(), sshKey); err != nil { //nolint:contextcheck
+				log.WithError(err).Errorf("failed to delete ssh key %s, delete it manually", builderName)
+			}
+		}
+	}()
+
+	api.sshRootUser = "root"
+
+	serverIP, err := api.waitForImageBuilder(ctx, builderName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := api.installScripts(api.sshRootUser, serverIP); err != nil {
+		return 0, err
+	}
+
+	log.Info("Executing common install...")
+
+	stdout, stderr, err := api.execCommand(serverIP, getCommonInstallCommand(config.NodeRoleImageBuilder))
+	if err != nil {
+		return 0, errors.Wrap(err, stderr)
+	}
+
+	log.Debugf(debugStdout, stdout)
+	log.Debugf(debugStderr, stderr)
+
+	// root login is disabled after common install
+	api.sshRootUser = config.Get().ServerComponents.Ubuntu.UserName
+
+	log.Info("Cleanup server...")
+
+	stdout, stderr, err = api.execCommand(serverIP, imageCleanupCommand)
+	if err != nil {
+		return 0, errors.Wrap(err, stderr)
+	}
+
+	log.Debugf(debugStdout, stdout)
+	log.Debugf(debugStderr, stderr)
+
+	log.Info("Shutdown server...")
+
+	action, _, err := api.hcloudClient.Server.Poweroff(ctx, serverResults.Server)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to poweroff server")
+	}
+
+	if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+		return 0, errors.Wrap(err, "error waiting server poweroff")
+	}
+
+	log.Info("Creating snapshot...")
+
+	description := fmt.Sprintf("%s kubernetes=%s containerd=%s %s",
+		config.Get().ServerComponents.Ubuntu.Version,
+		config.Get().ServerComponents.Kubernetes.Version,
+		config.Get().ServerComponents.Containerd.Version,
+		time.Now().Format(time.RFC3339),
+	)
+
+	imageResults, _, err := api.hcloudClient.Server.CreateImage(ctx, serverResults.Server, &hcloud.ServerCreateImageOpts{
+		Type:        hcloud.ImageTypeSnapshot,
+		Description: hcloud.Ptr(description),
+		Labels: map[string]string{
+			imageLabelPrefix + "ubuntu":       labelValue(config.Get().ServerComponents.Ubuntu.Version),
+			imageLabelPrefix + "architecture": labelValue(string(config.Get().ServerComponents.Ubuntu.Architecture)),
+			imageLabelPrefix + "kubernetes":   labelValue(config.Get().ServerComponents.Kubernetes.Version),
+			imageLabelPrefix + "containerd":   labelValue(config.Get().ServerComponents.Containerd.Version),
+			imageLabelPrefix + "docker":       labelValue(config.Get().ServerComponents.Docker.Version),
+		},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create snapshot")
+	}
+
+	if err := api.hcloudClient.Action.WaitFor(ctx, imageResults.Action); err != nil {
+		return 0, errors.Wrap(err, "error waiting snapshot")
+	}
+
+	log.Infof("Snapshot created! Use it in config serverComponents.ubuntu.snapshotid: %d", imageResults.Image.ID)
+
+	return imageResults.Image.ID, nil
+}
+
+// getImageBuilderSSHKey returns cluster ssh key or creates temporary one.
+func (api *ApplicationAPI) getImageBuilderSSHKey(ctx context.Context, builderName string) (*hcloud.SSHKey, error) {
+	sshKey, _, err := api.hcloudClient.SSHKey.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ssh key")
+	}
+
+	if sshKey != nil {
+		return sshKey, nil
+	}
+
+	publicKey, err := os.ReadFile(config.Get().SSHPublicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read ssh public key")
+	}
+
+	sshKey, _, err = api.hcloudClient.SSHKey.Create(ctx, hcloud.SSHKeyCreateOpts{
+		Name:      builderName,
+		PublicKey: string(publicKey),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ssh key")
+	}
+
+	return sshKey, nil
+}
+
+func (api *ApplicationAPI) waitForImageBuilder(ctx context.Context, serverName string) (string, error) {
+	retryCount := 0
+
+	for {
+		if ctx.Err() != nil {
+			return "", errors.Wrap(ctx.Err(), "context error")
+		}
+
+		if retryCount > config.Get().MasterServers.RetryTimeLimit {
+			return "", errRetryLimitReached
+		}
+
+		if retryCount > 0 {
+			utils.SleepContext(ctx, config.Get().MasterServers.WaitTimeInRetry)
+		}
+
+		retryCount++
+
+		log.Infof("Waiting for server... try=%03d", retryCount)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			log.WithError(err).Debug()
+
+			continue
+		}
+
+		return serverIP, nil
+	}
+}
+
+// labelValue returns valid hetzner label value from apt version string,
+// ubuntu release suffix is removed.
+func labelValue(value string) string {
+	result, _, _ := strings.Cut(value, "~")
+	result = labelValueRegexp.ReplaceAllString(result, "_")
+
+	if len(result) > labelValueMaxSize {
+		result = result[:labelValueMaxSize]
+	}
+
+	return strings.Trim(result, "._-")
+}
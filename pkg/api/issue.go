@@ -0,0 +1,171 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kubeconfig"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// minimal certificate lifetime allowed by CSR API.
+	issueKubeconfigMinTTL = 10 * time.Minute
+	// expirationSeconds in CSR API is int32.
+	issueKubeconfigMaxTTL      = math.MaxInt32 * time.Second
+	issueKubeconfigWaitTimeout = time.Minute
+)
+
+// IssueKubeconfig creates kubeconfig for user in groups with client certificate signed
+// by cluster CA through CertificateSigningRequest API, private key is never sent to cluster.
+func (api *ApplicationAPI) IssueKubeconfig(ctx context.Context, user, group string, ttl time.Duration, output string) error { //nolint:funlen,lll
+	if len(user) == 0 {
+		return errIssueKubeconfigUserNotSet
+	}
+
+	if ttl < issueKubeconfigMinTTL {
+		return errors.Wrapf(errIssueKubeconfigTTL, "minimal ttl is %s", issueKubeconfigMinTTL)
+	}
+
+	if ttl > issueKubeconfigMaxTTL {
+		return errors.Wrapf(errIssueKubeconfigTTL, "maximal ttl is %s", issueKubeconfigMaxTTL)
+	}
+
+	groups := make([]string, 0)
+
+	for _, item := range strings.Split(group, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			groups = append(groups, item)
+		}
+	}
+
+	if len(output) == 0 {
+		output = fmt.Sprintf("%s-%s.kubeconfig", config.Get().ClusterName, user)
+	}
+
+	adminKubeconfig, err := api.getAdminKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	clusterConfig, err := kubeconfig.Rename([]byte(adminKubeconfig), config.Get().ClusterName)
+	if err != nil {
+		return errors.Wrap(err, "error renaming kubeconfig")
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*clusterConfig, nil).ClientConfig()
+	if err != nil {
+		return errors.Wrap(err, "error creating client config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating clientset")
+	}
+
+	csrPEM, keyPEM, err := kubeconfig.NewUserCSR(user, groups)
+	if err != nil {
+		return errors.Wrap(err, "error creating certificate request")
+	}
+
+	expirationSeconds := int32(ttl.Seconds())
+
+	log.Infof("Creating certificate signing request for user=%s groups=%v ttl=%s", user, groups, ttl)
+
+	csrs := clientset.CertificatesV1().CertificateSigningRequests()
+
+	csr, err := csrs.Create(ctx, &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			// user name can be any string, server generates valid object name
+			GenerateName: "hcloud-k8s-ctl-",
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           csrPEM,
+			SignerName:        certificatesv1.KubeAPIServerClientSignerName,
+			ExpirationSeconds: &expirationSeconds,
+			Usages:            []certificatesv1.KeyUsage{certificatesv1.UsageClientAuth},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error creating certificate signing request")
+	}
+
+	csrName := csr.Name
+
+	log.Debugf("Created certificate signing request %s", csrName)
+
+	defer func() {
+		if err := csrs.Delete(context.WithoutCancel(ctx), csrName, metav1.DeleteOptions{}); err != nil {
+			log.WithError(err).Warnf("error deleting certificate signing request %s", csrName)
+		}
+	}()
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "HcloudK8sCtlApprove",
+		Message: "approved by hcloud-k8s-ctl issue-kubeconfig",
+	})
+
+	if _, err = csrs.UpdateApproval(ctx, csrName, csr, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "error approving certificate signing request")
+	}
+
+	var certPEM []byte
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, issueKubeconfigWaitTimeout, true, func(ctx context.Context) (bool, error) { //nolint:lll
+		csr, err := csrs.Get(ctx, csrName, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "error getting certificate signing request")
+		}
+
+		certPEM = csr.Status.Certificate
+
+		return len(certPEM) > 0, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error waiting for certificate")
+	}
+
+	notAfter, err := kubeconfig.CertificateNotAfter(certPEM)
+	if err != nil {
+		return errors.Wrap(err, "error reading issued certificate")
+	}
+
+	userConfig, err := kubeconfig.ForUser(clusterConfig, user, certPEM, keyPEM)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubeconfig")
+	}
+
+	if _, err = kubeconfig.Save(output, userConfig, false); err != nil {
+		return errors.Wrap(err, "error saving kubeconfig")
+	}
+
+	// signer can issue certificate with shorter lifetime than requested
+	log.Infof("Kubeconfig for %s saved to %s, valid until %s", user, output, notAfter.Format(time.RFC3339))
+
+	return nil
+}
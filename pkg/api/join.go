@@ -0,0 +1,106 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	joinTokenCommand        = "/root/scripts/join-token.sh"
+	joinTokenInstallCommand = "/root/scripts/join-token-install.sh"
+)
+
+//nolint:gochecknoglobals
+var joinTokenRe = regexp.MustCompile(`--token\s+(\S+)`)
+
+// createJoinCommand creates control-plane join command with fresh token and certificate key
+// on first master node, returns join command and token to delete after join.
+func (api *ApplicationAPI) createJoinCommand(ctx context.Context) (string, string, error) {
+	serverIP, err := api.waitForServer(ctx, fmt.Sprintf(config.Get().MasterServers.NamePattern, 1))
+	if err != nil {
+		return "", "", errors.Wrap(err, "first master node is not available")
+	}
+
+	log.Info("Creating join token...")
+
+	stdout, stderr, err := api.execCommandQuiet(serverIP, joinTokenCommand+" create")
+	if err != nil {
+		return "", "", errors.Wrap(err, stderr)
+	}
+
+	joinCommand := strings.TrimSpace(stdout)
+
+	token := joinTokenRe.FindStringSubmatch(joinCommand)
+	if len(token) != 2 { //nolint:mnd
+		return "", "", errJoinTokenNotFound
+	}
+
+	return joinCommand, token[1], nil
+}
+
+// deleteJoinToken deletes join token and uploaded certificates after join.
+func (api *ApplicationAPI) deleteJoinToken(ctx context.Context, token string) {
+	serverIP, err := api.waitForServer(ctx, fmt.Sprintf(config.Get().MasterServers.NamePattern, 1))
+	if err != nil {
+		log.WithError(err).Warn("error deleting join token, token will expire")
+
+		return
+	}
+
+	_, stderr, err := api.execCommandQuiet(serverIP, fmt.Sprintf("%s delete %s", joinTokenCommand, token))
+	if err != nil {
+		log.WithError(err).Warnf("error deleting join token, token will expire: %s", stderr)
+
+		return
+	}
+
+	log.Info("Join token deleted")
+}
+
+// installJoinTokenRefresh installs timer that refreshes worker join token on all master nodes.
+func (api *ApplicationAPI) installJoinTokenRefresh(ctx context.Context, copyNewScripts bool) error {
+	log.Info("Installing worker join token refresh...")
+
+	for i := 1; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			return errors.Wrapf(err, "error waiting %s", serverName)
+		}
+
+		if copyNewScripts {
+			if err = api.downloadNewScripts(serverName, serverIP); err != nil {
+				return errors.Wrapf(err, "error uploading scripts to %s", serverName)
+			}
+		}
+
+		stdout, stderr, err := api.execCommand(serverIP, joinTokenInstallCommand)
+		if err != nil {
+			return errors.Wrapf(err, "error installing join token refresh on %s: %s", serverName, stderr)
+		}
+
+		log.Debugf(debugStdout, stdout)
+		log.Debugf(debugStderr, stderr)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	knownHostsFileMode = 0o600
+	knownHostsDirMode  = 0o700
+)
+
+// hostKeyCallback pins server host key on first connection, keys are stored
+// by server ID because IPs of deleted servers are reused for new servers.
+func (api *ApplicationAPI) hostKeyCallback(ipAddress string) ssh.HostKeyCallback {
+	return func(_ string, remote net.Addr, key ssh.PublicKey) error {
+		api.knownHostsMutex.Lock()
+		defer api.knownHostsMutex.Unlock()
+
+		path := config.Get().SSHKnownHosts
+		host := api.knownHostName(ipAddress)
+
+		if err := createKnownHosts(path); err != nil {
+			return err
+		}
+
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			return errors.Wrap(err, "error reading known hosts")
+		}
+
+		err = callback(net.JoinHostPort(host, "22"), remote, key)
+
+		var keyError *knownhosts.KeyError
+
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &keyError) && len(keyError.Want) == 0:
+			log.WithField("server", api.serverName(ipAddress)).Infof("Pinning host key %s", ssh.FingerprintSHA256(key))
+
+			return appendKnownHost(path, host, key)
+		case errors.As(err, &keyError):
+			return errors.Wrapf(errHostKeyMismatch, "%s (%s), remove it from %s if server was rebuilt", host, ipAddress, path)
+		default:
+			return errors.Wrap(err, "error checking host key")
+		}
+	}
+}
+
+// knownHostName returns name of server in known hosts, IP if server is unknown.
+func (api *ApplicationAPI) knownHostName(ipAddress string) string {
+	if server, ok := api.servers.Load(ipAddress); ok {
+		return fmt.Sprintf("hcloud-server-%d", server.(*hcloud.Server).ID) //nolint:forcetypeassert
+	}
+
+	return ipAddress
+}
+
+func createKnownHosts(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), knownHostsDirMode); err != nil {
+		return errors.Wrap(err, "error creating known hosts directory")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, knownHostsFileMode)
+	if err != nil {
+		return errors.Wrap(err, "error creating known hosts")
+	}
+
+	return errors.Wrap(file.Close(), "error closing known hosts")
+}
+
+func appendKnownHost(path string, host string, key ssh.PublicKey) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, knownHostsFileMode)
+	if err != nil {
+		return errors.Wrap(err, "error opening known hosts")
+	}
+
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, knownhosts.Line([]string{host}, key)); err != nil {
+		return errors.Wrap(err, "error writing known hosts")
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kube"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// master node must be ready after upgrade
+	nodeReadyTimeout = 5 * time.Minute
+	// autoscaler must not remove master nodes
+	scaleDownDisabledAnnotation = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+)
+
+// kubeClient returns client of cluster, kubeconfig is taken from created cluster,
+// saved kubeconfig or first available master node.
+func (api *ApplicationAPI) kubeClient(ctx context.Context) (*kube.Client, error) {
+	if len(api.clusterKubeConfig) > 0 {
+		return kube.NewFromKubeconfig([]byte(api.clusterKubeConfig), "")
+	}
+
+	if savedKubeconfig, err := os.ReadFile(config.Get().KubeConfigPath); err == nil {
+		client, err := kube.NewFromKubeconfig(savedKubeconfig, config.Get().ClusterName)
+		if err == nil {
+			return client, nil
+		}
+
+		log.WithError(err).Debug("saved kubeconfig can not be used")
+	}
+
+	adminKubeconfig, err := api.getAdminKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	api.clusterKubeConfig = adminKubeconfig
+
+	return kube.NewFromKubeconfig([]byte(adminKubeconfig), "")
+}
+
+// annotateMasters disables scale down of master nodes by autoscaler.
+func (api *ApplicationAPI) annotateMasters(ctx context.Context) error {
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]string{
+		scaleDownDisabledAnnotation: "true",
+	}
+
+	patched, err := kubeClient.PatchNodes(ctx, kube.LabelControlPlane, nil, annotations)
+	if err != nil {
+		return errors.Wrap(err, "error annotating master nodes")
+	}
+
+	// legacy masters that have only master label
+	patchedLegacy, err := kubeClient.PatchNodes(ctx, kube.LabelMaster+",!"+kube.LabelControlPlane, nil, annotations)
+	if err != nil {
+		return errors.Wrap(err, "error annotating legacy master nodes")
+	}
+
+	log.Infof("Annotated %d master nodes", patched+patchedLegacy)
+
+	return nil
+}
+
+// waitNodeReady waits node is Ready in kubernetes.
+func (api *ApplicationAPI) waitNodeReady(ctx context.Context, node string) error {
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Waiting for node %s ready...", node)
+
+	return kubeClient.WaitNodesReady(ctx, kube.LabelHostname+"="+node, 1, nodeReadyTimeout)
+}
+
+// Status returns nodes and unhealthy kube-system pods of cluster.
+func (api *ApplicationAPI) Status(ctx context.Context) (*ClusterStatus, error) {
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := kubeClient.Nodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting nodes")
+	}
+
+	pods, err := kubeClient.UnhealthyPods(ctx, metav1.NamespaceSystem)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting pods")
+	}
+
+	for _, pod := range pods {
+		log.Warnf("Pod %s/%s on %s is %s, ready %s, restarts %d", pod.Namespace, pod.Name, pod.Node, pod.Phase, pod.Ready, pod.Restarts) //nolint:lll
+	}
+
+	return &ClusterStatus{
+		Nodes:         nodes,
+		UnhealthyPods: pods,
+	}, nil
+}
+
+// Drain cordons node and evicts pods from it, uncordon makes node schedulable again.
+func (api *ApplicationAPI) Drain(ctx context.Context, node string, timeout time.Duration, uncordon bool) error {
+	if len(node) == 0 {
+		return errNodeNotSet
+	}
+
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if uncordon {
+		log.Infof("Uncordon node %s...", node)
+
+		return errors.Wrap(kubeClient.SetUnschedulable(ctx, node, false), "error uncordon node")
+	}
+
+	log.Infof("Draining node %s...", node)
+
+	if err := kubeClient.Drain(ctx, node, timeout); err != nil {
+		return errors.Wrap(err, "error draining node")
+	}
+
+	log.Infof("Node %s drained", node)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kubeconfig"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const adminKubeconfigPath = "/etc/kubernetes/admin.conf"
+
+// GetKubeconfig downloads admin kubeconfig from first available master node.
+func (api *ApplicationAPI) GetKubeconfig(ctx context.Context) error {
+	adminKubeconfig, err := api.getAdminKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	api.clusterKubeConfig = adminKubeconfig
+
+	return api.saveKubeconfig()
+}
+
+// getAdminKubeconfig returns admin kubeconfig from first available master node.
+func (api *ApplicationAPI) getAdminKubeconfig(ctx context.Context) (string, error) {
+	for i := 1; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		log := log.WithField("master", serverName)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			log.WithError(err).Warn("master node is not available")
+
+			continue
+		}
+
+		stdout, stderr, err := api.execCommandQuiet(serverIP, "cat "+adminKubeconfigPath)
+		if err != nil {
+			log.WithError(err).Warn(stderr)
+
+			continue
+		}
+
+		return stdout, nil
+	}
+
+	return "", errNoHealthyMaster
+}
+
+// saveKubeconfig saves kubeconfig with context, cluster and user named as cluster.
+func (api *ApplicationAPI) saveKubeconfig() error {
+	clusterConfig, err := kubeconfig.Rename([]byte(api.clusterKubeConfig), config.Get().ClusterName)
+	if err != nil {
+		return errors.Wrap(err, "error renaming kubeconfig")
+	}
+
+	log.Infof("Saving kubeconfig to %s (context %s)", config.Get().KubeConfigPath, config.Get().ClusterName)
+
+	backupPath, err := kubeconfig.Save(config.Get().KubeConfigPath, clusterConfig, config.Get().KubeConfigMerge)
+	if err != nil {
+		return errors.Wrap(err, "error saving kubeconfig")
+	}
+
+	if len(backupPath) > 0 {
+		log.Infof("Previous kubeconfig saved to %s", backupPath)
+	}
+
+	return nil
+}
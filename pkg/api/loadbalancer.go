@@ -0,0 +1,342 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"slices"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *ApplicationAPI) createLoadBalancer(ctx context.Context) error {
+	log.Info("Creating loadbalancer...")
+
+	k8sLoadBalancerType, _, err := api.hcloudClient.LoadBalancerType.Get(
+		ctx,
+		config.Get().MasterLoadBalancer.LoadBalancerType,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not get loadbalancer type")
+	}
+
+	k8sLocation, _, err := api.hcloudClient.Location.Get(ctx, config.Get().Location)
+	if err != nil {
+		return errors.Wrap(err, "could not get location")
+	}
+
+	k8sNetwork, _, err := api.hcloudClient.Network.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return errors.Wrap(err, "could not get network")
+	}
+
+	result, _, err := api.hcloudClient.LoadBalancer.Create(ctx, hcloud.LoadBalancerCreateOpts{
+		Name:             config.Get().ClusterName,
+		LoadBalancerType: k8sLoadBalancerType,
+		Algorithm: &hcloud.LoadBalancerAlgorithm{
+			Type: config.Get().MasterLoadBalancer.Algorithm,
+		},
+		Location: k8sLocation,
+		Network:  k8sNetwork,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create loadbalancer")
+	}
+
+	if err := api.hcloudClient.Action.WaitFor(ctx, result.Action); err != nil {
+		return errors.Wrap(err, "error waiting loadbalancer")
+	}
+
+	return api.reconcileLoadBalancer(ctx, result.LoadBalancer)
+}
+
+// UpdateLoadBalancer applies algorithm, services and targets from config to existing loadbalancer.
+func (api *ApplicationAPI) UpdateLoadBalancer(ctx context.Context) error {
+	log.Info("Updating loadbalancer...")
+
+	loadBalancer, _, err := api.hcloudClient.LoadBalancer.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return errors.Wrap(err, "could not get loadbalancer")
+	}
+
+	if loadBalancer == nil {
+		return errors.Errorf("loadbalancer %s not found", config.Get().ClusterName)
+	}
+
+	if err := api.reconcileLoadBalancer(ctx, loadBalancer); err != nil {
+		return err
+	}
+
+	log.Info("Loadbalancer updated!")
+
+	return nil
+}
+
+func (api *ApplicationAPI) reconcileLoadBalancer(ctx context.Context, loadBalancer *hcloud.LoadBalancer) error {
+	algorithm := config.Get().MasterLoadBalancer.Algorithm
+
+	if loadBalancer.Algorithm.Type != algorithm {
+		log.Infof("Changing loadbalancer algorithm to %s", algorithm)
+
+		action, _, err := api.hcloudClient.LoadBalancer.ChangeAlgorithm(ctx, loadBalancer, hcloud.LoadBalancerChangeAlgorithmOpts{
+			Type: algorithm,
+		})
+		if err != nil {
+			return errors.Wrap(err, "could not change loadbalancer algorithm")
+		}
+
+		if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+			return errors.Wrap(err, "error waiting loadbalancer algorithm")
+		}
+	}
+
+	if err := api.reconcileLoadBalancerServices(ctx, loadBalancer); err != nil {
+		return err
+	}
+
+	return api.reconcileLoadBalancerTargets(ctx, loadBalancer)
+}
+
+// reconcileLoadBalancerServices adds or updates services from config and deletes services not in config.
+func (api *ApplicationAPI) reconcileLoadBalancerServices(ctx context.Context, loadBalancer *hcloud.LoadBalancer) error {
+	existingServices := make(map[int]bool)
+
+	for _, service := range loadBalancer.Services {
+		existingServices[service.ListenPort] = true
+	}
+
+	configServices := make(map[int]bool)
+
+	for _, service := range getLoadBalancerServices() {
+		var (
+			action *hcloud.Action
+			err    error
+		)
+
+		configServices[*service.ListenPort] = true
+
+		if existingServices[*service.ListenPort] {
+			log.Infof("Updating loadbalancer service %d", *service.ListenPort)
+
+			action, _, err = api.hcloudClient.LoadBalancer.UpdateService(ctx, loadBalancer, *service.ListenPort, toUpdateServiceOpts(service)) //nolint:lll
+		} else {
+			log.Infof("Adding loadbalancer service %d", *service.ListenPort)
+
+			action, _, err = api.hcloudClient.LoadBalancer.AddService(ctx, loadBalancer, service)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "could not apply loadbalancer service %d", *service.ListenPort)
+		}
+
+		if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+			return errors.Wrapf(err, "error waiting loadbalancer service %d", *service.ListenPort)
+		}
+	}
+
+	for _, service := range loadBalancer.Services {
+		if configServices[service.ListenPort] {
+			continue
+		}
+
+		log.Infof("Deleting loadbalancer service %d", service.ListenPort)
+
+		action, _, err := api.hcloudClient.LoadBalancer.DeleteService(ctx, loadBalancer, service.ListenPort)
+		if err != nil {
+			return errors.Wrapf(err, "could not delete loadbalancer service %d", service.ListenPort)
+		}
+
+		if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+			return errors.Wrapf(err, "error waiting loadbalancer service %d", service.ListenPort)
+		}
+	}
+
+	return nil
+}
+
+// reconcileLoadBalancerTargets adds label selector targets from config and removes label selector targets
+// not in config, server targets of master nodes are not changed.
+func (api *ApplicationAPI) reconcileLoadBalancerTargets(ctx context.Context, loadBalancer *hcloud.LoadBalancer) error {
+	configSelectors := config.Get().MasterLoadBalancer.LabelSelectorTargets
+	existingSelectors := make([]string, 0)
+
+	for _, target := range loadBalancer.Targets {
+		if target.Type == hcloud.LoadBalancerTargetTypeLabelSelector && target.LabelSelector != nil {
+			existingSelectors = append(existingSelectors, target.LabelSelector.Selector)
+		}
+	}
+
+	for _, selector := range configSelectors {
+		if slices.Contains(existingSelectors, selector) {
+			continue
+		}
+
+		log.Infof("Adding loadbalancer target %s", selector)
+
+		action, _, err := api.hcloudClient.LoadBalancer.AddLabelSelectorTarget(ctx, loadBalancer, hcloud.LoadBalancerAddLabelSelectorTargetOpts{ //nolint:lll
+			Selector:     selector,
+			UsePrivateIP: hcloud.Ptr(true),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "could not add loadbalancer target %s", selector)
+		}
+
+		if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+			return errors.Wrapf(err, "error waiting loadbalancer target %s", selector)
+		}
+	}
+
+	for _, selector := range existingSelectors {
+		if slices.Contains(configSelectors, selector) {
+			continue
+		}
+
+		log.Infof("Removing loadbalancer target %s", selector)
+
+		action, _, err := api.hcloudClient.LoadBalancer.RemoveLabelSelectorTarget(ctx, loadBalancer, selector)
+		if err != nil {
+			return errors.Wrapf(err, "could not remove loadbalancer target %s", selector)
+		}
+
+		if err := api.hcloudClient.Action.WaitFor(ctx, action); err != nil {
+			return errors.Wrapf(err, "error waiting loadbalancer target %s", selector)
+		}
+	}
+
+	return nil
+}
+
+func (api *ApplicationAPI) attachToBalancer(ctx context.Context, server hcloud.ServerCreateResult, balancer *hcloud.LoadBalancer) error { //nolint:lll
+	usePrivateIP := true
+	k8sTargetServer := hcloud.LoadBalancerAddServerTargetOpts{
+		Server:       server.Server,
+		UsePrivateIP: &usePrivateIP,
+	}
+
+	_, _, err := api.hcloudClient.LoadBalancer.AddServerTarget(ctx, balancer, k8sTargetServer)
+	if err != nil {
+		return errors.Wrap(err, "could not attach server to loadbalancer")
+	}
+
+	return nil
+}
+
+// getLoadBalancerServices returns kubernetes API service and all extra services from config.
+func getLoadBalancerServices() []hcloud.LoadBalancerAddServiceOpts {
+	masterLoadBalancer := config.Get().MasterLoadBalancer
+
+	services := []hcloud.LoadBalancerAddServiceOpts{
+		{
+			Protocol:        hcloud.LoadBalancerServiceProtocolTCP,
+			ListenPort:      hcloud.Ptr(masterLoadBalancer.ListenPort),
+			DestinationPort: hcloud.Ptr(masterLoadBalancer.DestinationPort),
+			HealthCheck:     getLoadBalancerHealthCheck(masterLoadBalancer.HealthCheck, masterLoadBalancer.ListenPort),
+		},
+	}
+
+	for _, service := range masterLoadBalancer.Services {
+		healthCheck := service.HealthCheck
+
+		if len(healthCheck.Protocol) == 0 {
+			healthCheck.Protocol = hcloud.LoadBalancerServiceProtocolTCP
+		}
+
+		if healthCheck.Interval == 0 {
+			healthCheck.Interval = masterLoadBalancer.HealthCheck.Interval
+		}
+
+		if healthCheck.Timeout == 0 {
+			healthCheck.Timeout = masterLoadBalancer.HealthCheck.Timeout
+		}
+
+		if healthCheck.Retries == 0 {
+			healthCheck.Retries = masterLoadBalancer.HealthCheck.Retries
+		}
+
+		services = append(services, hcloud.LoadBalancerAddServiceOpts{
+			Protocol:        service.Protocol,
+			ListenPort:      hcloud.Ptr(service.ListenPort),
+			DestinationPort: hcloud.Ptr(service.DestinationPort),
+			Proxyprotocol:   hcloud.Ptr(service.ProxyProtocol),
+			HealthCheck:     getLoadBalancerHealthCheck(healthCheck, service.DestinationPort),
+		})
+	}
+
+	return services
+}
+
+func getLoadBalancerHealthCheck(healthCheck config.LoadBalancerHealthCheck, defaultPort int) *hcloud.LoadBalancerAddServiceOptsHealthCheck { //nolint:lll
+	port := healthCheck.Port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	result := &hcloud.LoadBalancerAddServiceOptsHealthCheck{
+		Protocol: healthCheck.Protocol,
+		Port:     hcloud.Ptr(port),
+		Interval: hcloud.Ptr(healthCheck.Interval),
+		Timeout:  hcloud.Ptr(healthCheck.Timeout),
+		Retries:  hcloud.Ptr(healthCheck.Retries),
+	}
+
+	if healthCheck.Protocol == hcloud.LoadBalancerServiceProtocolHTTP {
+		path := healthCheck.Path
+		if len(path) == 0 {
+			path = "/"
+		}
+
+		statusCodes := healthCheck.StatusCodes
+		if len(statusCodes) == 0 {
+			statusCodes = []string{"2??", "3??"}
+		}
+
+		result.HTTP = &hcloud.LoadBalancerAddServiceOptsHealthCheckHTTP{
+			Path:        hcloud.Ptr(path),
+			StatusCodes: statusCodes,
+			TLS:         hcloud.Ptr(healthCheck.TLS),
+		}
+	}
+
+	return result
+}
+
+func toUpdateServiceOpts(service hcloud.LoadBalancerAddServiceOpts) hcloud.LoadBalancerUpdateServiceOpts {
+	result := hcloud.LoadBalancerUpdateServiceOpts{
+		Protocol:        service.Protocol,
+		DestinationPort: service.DestinationPort,
+		Proxyprotocol:   service.Proxyprotocol,
+	}
+
+	if healthCheck := service.HealthCheck; healthCheck != nil {
+		result.HealthCheck = &hcloud.LoadBalancerUpdateServiceOptsHealthCheck{
+			Protocol: healthCheck.Protocol,
+			Port:     healthCheck.Port,
+			Interval: healthCheck.Interval,
+			Timeout:  healthCheck.Timeout,
+			Retries:  healthCheck.Retries,
+		}
+
+		if healthCheck.HTTP != nil {
+			result.HealthCheck.HTTP = &hcloud.LoadBalancerUpdateServiceOptsHealthCheckHTTP{
+				Path:        healthCheck.HTTP.Path,
+				StatusCodes: healthCheck.HTTP.StatusCodes,
+				TLS:         healthCheck.HTTP.TLS,
+			}
+		}
+	}
+
+	return result
+}
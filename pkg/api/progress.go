@@ -0,0 +1,98 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	progressInterval = 30 * time.Second
+	// phases of NewCluster
+	newClusterPhases = 9
+)
+
+// progress logs current phase of long running action,
+// running phase is logged periodically with elapsed time.
+type progress struct {
+	total      int
+	current    int
+	phase      string
+	start      time.Time
+	phaseStart time.Time
+	stop       chan struct{}
+}
+
+func newProgress(total int) *progress {
+	return &progress{
+		total: total,
+		start: time.Now(),
+	}
+}
+
+// next finishes current phase and starts new one.
+func (p *progress) next(phase string) {
+	p.finishPhase()
+
+	p.current++
+	p.phase = phase
+	p.phaseStart = time.Now()
+	p.stop = make(chan struct{})
+
+	log.Infof("[%d/%d] %s...", p.current, p.total, p.phase)
+
+	go func(current int, phase string, phaseStart time.Time, stop chan struct{}) {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				log.Infof("[%d/%d] %s, still running %s", current, p.total, phase, elapsed(phaseStart))
+			}
+		}
+	}(p.current, p.phase, p.phaseStart, p.stop)
+}
+
+// done finishes last phase.
+func (p *progress) done() {
+	p.finishPhase()
+
+	log.Infof("All %d phases done in %s", p.total, elapsed(p.start))
+}
+
+// close stops logging of current phase, used when action failed.
+func (p *progress) close() {
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *progress) finishPhase() {
+	if p.stop == nil {
+		return
+	}
+
+	p.close()
+
+	log.Infof("[%d/%d] %s done in %s", p.current, p.total, p.phase, elapsed(p.phaseStart))
+}
+
+func elapsed(start time.Time) time.Duration {
+	return time.Since(start).Round(time.Second)
+}
@@ -0,0 +1,346 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/s3"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	etcdRestoreCommand      = "/root/scripts/etcd-restore.sh"
+	etcdRestoreSnapshotPath = "/var/lib/etcd-restore/snapshot.db"
+	etcdLatestSnapshot      = "latest"
+)
+
+type restoreMaster struct {
+	name string
+	ip   string
+}
+
+// RestoreEtcd restores etcd cluster on all master nodes from snapshot, snapshot is a local file,
+// s3://bucket/key or latest snapshot from backup storage.
+func (api *ApplicationAPI) RestoreEtcd(ctx context.Context, snapshot string) error { //nolint:funlen,cyclop
+	log.Info("Restoring etcd...")
+
+	snapshotFile, err := openEtcdSnapshot(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+
+	defer snapshotFile.Close()
+
+	checksum, err := fileChecksum(snapshotFile)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Snapshot sha256=%s", checksum)
+
+	// all members must be restored from same snapshot
+	masters := make([]restoreMaster, 0)
+
+	for i := 1; i <= config.Get().MasterCount; i++ {
+		serverName := fmt.Sprintf(config.Get().MasterServers.NamePattern, i)
+
+		serverIP, err := api.waitForServer(ctx, serverName)
+		if err != nil {
+			return errors.Wrapf(err, "master %s is not available", serverName)
+		}
+
+		if err = api.downloadNewScripts(serverName, serverIP); err != nil {
+			return errors.Wrapf(err, "error uploading scripts to %s", serverName)
+		}
+
+		masters = append(masters, restoreMaster{name: serverName, ip: serverIP})
+	}
+
+	members := make([]string, 0, len(masters))
+
+	for _, master := range masters {
+		stdout, stderr, err := api.execCommand(master.ip, etcdRestoreCommand+" member")
+		if err != nil {
+			return errors.Wrapf(err, "error getting etcd member on %s: %s", master.name, stderr)
+		}
+
+		members = append(members, strings.TrimSpace(stdout))
+	}
+
+	initialCluster := strings.Join(members, ",")
+
+	log.Infof("Initial cluster %s", initialCluster)
+
+	for _, master := range masters {
+		log.Infof("Uploading snapshot to %s...", master.name)
+
+		if _, err := snapshotFile.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "error reading snapshot")
+		}
+
+		if err := api.uploadFile(api.sshRootUser, master.ip, etcdRestoreSnapshotPath, snapshotFile); err != nil {
+			return errors.Wrapf(err, "error uploading snapshot to %s", master.name)
+		}
+	}
+
+	restoreEnv := fmt.Sprintf("INITIAL_CLUSTER=%s INITIAL_CLUSTER_TOKEN=%s-restore-%d SNAPSHOT_SHA256=%s",
+		initialCluster,
+		config.Get().ClusterName,
+		time.Now().Unix(),
+		checksum,
+	)
+
+	log.Warn("Stopping control-plane on all master nodes...")
+
+	for _, master := range masters {
+		if err := api.execRestoreStep(master, "stop", ""); err != nil {
+			api.recoverControlPlane(masters, restoreEnv)
+
+			return err
+		}
+	}
+
+	if err := api.swapEtcdData(masters, restoreEnv); err != nil {
+		api.recoverControlPlane(masters, restoreEnv)
+
+		return err
+	}
+
+	// all members have restored data, first master starts new cluster, other members join it
+	for _, master := range masters {
+		log.Infof("Starting control-plane on %s...", master.name)
+
+		if err := api.execRestoreStep(master, "start", ""); err != nil {
+			log.WithError(err).Error("Etcd data is restored on all master nodes, starting control-plane again...")
+
+			api.startStoppedMasters(masters)
+
+			return err
+		}
+	}
+
+	if err := api.waitForAPIHealth(ctx, masters[0]); err != nil {
+		return errors.Wrap(err, "etcd data is restored on all master nodes, but kubernetes API is not healthy")
+	}
+
+	log.Info("Etcd restored!")
+
+	return nil
+}
+
+// swapEtcdData restores snapshot to staging directory on all master nodes,
+// etcd data is replaced only after every member was restored successfully.
+func (api *ApplicationAPI) swapEtcdData(masters []restoreMaster, restoreEnv string) error {
+	for _, master := range masters {
+		log.Infof("Restoring etcd member on %s...", master.name)
+
+		if err := api.execRestoreStep(master, "prepare", restoreEnv); err != nil {
+			return err
+		}
+	}
+
+	for _, master := range masters {
+		log.Infof("Replacing etcd data on %s...", master.name)
+
+		if err := api.execRestoreStep(master, "swap", restoreEnv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recoverControlPlane returns previous etcd data on members that were replaced by current restore
+// and starts control-plane, so all members have same data as before restore.
+func (api *ApplicationAPI) recoverControlPlane(masters []restoreMaster, restoreEnv string) {
+	log.Warn("Restore failed, returning previous etcd data on all master nodes...")
+
+	rolledBack := make([]restoreMaster, 0, len(masters))
+
+	for _, master := range masters {
+		if err := api.execRestoreStep(master, "rollback", restoreEnv); err != nil {
+			// starting member with unknown data can split etcd cluster
+			log.WithError(err).Errorf("error returning previous etcd data on %s, control-plane is not started, "+
+				"previous etcd data is in /var/lib/etcd-before-restore-* directory on node",
+				master.name,
+			)
+
+			continue
+		}
+
+		rolledBack = append(rolledBack, master)
+	}
+
+	api.startStoppedMasters(rolledBack)
+}
+
+// startStoppedMasters moves control-plane manifests back on master nodes.
+func (api *ApplicationAPI) startStoppedMasters(masters []restoreMaster) {
+	log.Warn("Starting control-plane on master nodes...")
+
+	for _, master := range masters {
+		if err := api.execRestoreStep(master, "start", ""); err != nil {
+			log.WithError(err).Errorf("error starting control-plane on %s, to recover manually run on node: "+
+				"sudo mv /etc/kubernetes/manifests-restore/*.yaml /etc/kubernetes/manifests/ && sudo systemctl restart kubelet",
+				master.name,
+			)
+		}
+	}
+}
+
+// execRestoreStep executes step of etcd-restore.sh on master node, env is a list of variables for step.
+func (api *ApplicationAPI) execRestoreStep(master restoreMaster, step string, env string) error {
+	command := etcdRestoreCommand + " " + step
+	if len(env) > 0 {
+		command = env + " " + command
+	}
+
+	stdout, stderr, err := api.execCommand(master.ip, command)
+	if err != nil {
+		return errors.Wrapf(err, "error executing %s on %s: %s", step, master.name, stderr)
+	}
+
+	log.Debugf(debugStdout, stdout)
+	log.Debugf(debugStderr, stderr)
+
+	return nil
+}
+
+// waitForAPIHealth checks kubernetes API through load balancer.
+func (api *ApplicationAPI) waitForAPIHealth(ctx context.Context, master restoreMaster) error {
+	retryCount := 0
+
+	for {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "context error")
+		}
+
+		if retryCount > config.Get().MasterServers.RetryTimeLimit {
+			return errRetryLimitReached
+		}
+
+		if retryCount > 0 {
+			utils.SleepContext(ctx, config.Get().MasterServers.WaitTimeInRetry)
+		}
+
+		retryCount++
+
+		log.Infof("Waiting for kubernetes API... try=%03d", retryCount)
+
+		stdout, _, err := api.execCommandQuiet(master.ip, etcdRestoreCommand+" health")
+		if err != nil {
+			log.WithError(err).Debug()
+
+			continue
+		}
+
+		log.Infof("Kubernetes API is %s", strings.TrimSpace(stdout))
+
+		return nil
+	}
+}
+
+// openEtcdSnapshot returns snapshot file, snapshot from storage is downloaded to temporary file
+// that is removed after close.
+func openEtcdSnapshot(ctx context.Context, snapshot string) (*os.File, error) {
+	backup := config.Get().Backup
+
+	if snapshot != etcdLatestSnapshot && !strings.HasPrefix(snapshot, "s3://") {
+		snapshotFile, err := os.Open(snapshot)
+		if err != nil {
+			return nil, errors.Wrap(err, "error opening snapshot")
+		}
+
+		return snapshotFile, nil
+	}
+
+	bucket := backup.Bucket
+	key := ""
+
+	if snapshot != etcdLatestSnapshot {
+		snapshotURL, err := url.Parse(snapshot)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing snapshot url")
+		}
+
+		bucket = snapshotURL.Host
+		key = strings.TrimPrefix(snapshotURL.Path, "/")
+	}
+
+	storage, err := s3.New(backup.Endpoint, backup.Region, bucket, backup.AccessKey, backup.SecretKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating backup storage client")
+	}
+
+	if len(key) == 0 {
+		objects, err := storage.ListObjects(ctx, path.Join(backup.Prefix, etcdSnapshotPrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing snapshots")
+		}
+
+		if len(objects) == 0 {
+			return nil, errNoSnapshots
+		}
+
+		key = objects[len(objects)-1].Key
+	}
+
+	log.Infof("Downloading snapshot %s/%s...", bucket, key)
+
+	snapshotFile, err := os.CreateTemp("", etcdSnapshotPrefix+"*.db")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating temporary file")
+	}
+
+	// file is still available for reading until closed
+	if err := os.Remove(snapshotFile.Name()); err != nil {
+		_ = snapshotFile.Close()
+
+		return nil, errors.Wrap(err, "error removing temporary file")
+	}
+
+	if err := storage.GetObject(ctx, key, snapshotFile); err != nil {
+		_ = snapshotFile.Close()
+
+		return nil, errors.Wrap(err, "error downloading snapshot")
+	}
+
+	return snapshotFile, nil
+}
+
+func fileChecksum(file *os.File) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", errors.Wrap(err, "error reading file")
+	}
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.Wrap(err, "error reading file")
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
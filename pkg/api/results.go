@@ -0,0 +1,398 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"strconv"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/fanout"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kube"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/pricing"
+)
+
+type Datacenter struct {
+	Name     string `json:"name"     yaml:"name"`
+	Location string `json:"location" yaml:"location"`
+}
+
+// Configurations are available locations, datacenters and server types.
+type Configurations struct {
+	Locations   []string     `json:"locations"   yaml:"locations"`
+	Datacenters []Datacenter `json:"datacenters" yaml:"datacenters"`
+	ServerTypes []string     `json:"serverTypes" yaml:"serverTypes"`
+}
+
+func (c *Configurations) Header() []string {
+	return []string{"TYPE", "NAME", "LOCATION"}
+}
+
+func (c *Configurations) Rows() [][]string {
+	rows := make([][]string, 0)
+
+	for _, location := range c.Locations {
+		rows = append(rows, []string{"location", location, location})
+	}
+
+	for _, datacenter := range c.Datacenters {
+		rows = append(rows, []string{"datacenter", datacenter.Name, datacenter.Location})
+	}
+
+	for _, serverType := range c.ServerTypes {
+		rows = append(rows, []string{"servertype", serverType, ""})
+	}
+
+	return rows
+}
+
+// Resource is created cloud resource.
+type Resource struct {
+	Type string `json:"type"         yaml:"type"`
+	Name string `json:"name"         yaml:"name"`
+	ID   int64  `json:"id"           yaml:"id"`
+	IP   string `json:"ip,omitempty" yaml:"ip,omitempty"`
+}
+
+type Resources []Resource
+
+func (r Resources) Header() []string {
+	return []string{"TYPE", "NAME", "ID", "IP"}
+}
+
+func (r Resources) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, resource := range r {
+		rows = append(rows, []string{resource.Type, resource.Name, strconv.FormatInt(resource.ID, 10), resource.IP})
+	}
+
+	return rows
+}
+
+const (
+	AdhocStatusOK       = fanout.StatusOK
+	AdhocStatusFailed   = fanout.StatusFailed
+	AdhocStatusCanceled = fanout.StatusCanceled
+)
+
+// AdhocResult is result of command on one server.
+type AdhocResult struct {
+	Server   string `json:"server"          yaml:"server"`
+	IP       string `json:"ip"              yaml:"ip"`
+	Status   string `json:"status"          yaml:"status"`
+	ExitCode int    `json:"exitCode"        yaml:"exitCode"`
+	Duration string `json:"duration"        yaml:"duration"`
+	Stdout   string `json:"stdout"          yaml:"stdout"`
+	Stderr   string `json:"stderr"          yaml:"stderr"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type AdhocResults []AdhocResult
+
+// Failed returns number of servers where command failed or was canceled.
+func (r AdhocResults) Failed() int {
+	failed := 0
+
+	for _, result := range r {
+		if result.Status != AdhocStatusOK {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (r AdhocResults) Header() []string {
+	return []string{"SERVER", "IP", "STATUS", "EXIT-CODE", "DURATION", "ERROR"}
+}
+
+func (r AdhocResults) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, result := range r {
+		rows = append(rows, []string{
+			result.Server,
+			result.IP,
+			result.Status,
+			strconv.Itoa(result.ExitCode),
+			result.Duration,
+			result.Error,
+		})
+	}
+
+	return rows
+}
+
+// CopyResult is result of file copy to or from one server.
+type CopyResult struct {
+	Server      string `json:"server"          yaml:"server"`
+	IP          string `json:"ip"              yaml:"ip"`
+	Status      string `json:"status"          yaml:"status"`
+	Source      string `json:"source"          yaml:"source"`
+	Destination string `json:"destination"     yaml:"destination"`
+	Size        int64  `json:"size"            yaml:"size"`
+	SHA256      string `json:"sha256"          yaml:"sha256"`
+	Duration    string `json:"duration"        yaml:"duration"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type CopyResults []CopyResult
+
+// Failed returns number of servers where copy failed or was canceled.
+func (r CopyResults) Failed() int {
+	failed := 0
+
+	for _, result := range r {
+		if result.Status != fanout.StatusOK {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (r CopyResults) Header() []string {
+	return []string{"SERVER", "STATUS", "DESTINATION", "SIZE", "SHA256", "DURATION", "ERROR"}
+}
+
+func (r CopyResults) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, result := range r {
+		rows = append(rows, []string{
+			result.Server,
+			result.Status,
+			result.Destination,
+			strconv.FormatInt(result.Size, 10),
+			result.SHA256,
+			result.Duration,
+			result.Error,
+		})
+	}
+
+	return rows
+}
+
+// Diagnostics is archive with diagnostics of servers.
+type Diagnostics struct {
+	Archive string              `json:"archive" yaml:"archive"`
+	Servers []DiagnosticsServer `json:"servers" yaml:"servers"`
+}
+
+type DiagnosticsServer struct {
+	Server   string `json:"server"          yaml:"server"`
+	Status   string `json:"status"          yaml:"status"`
+	Duration string `json:"duration"        yaml:"duration"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Failed returns number of servers where diagnostics was not collected.
+func (d *Diagnostics) Failed() int {
+	failed := 0
+
+	for _, server := range d.Servers {
+		if server.Status != fanout.StatusOK {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (d *Diagnostics) Header() []string {
+	return []string{"SERVER", "STATUS", "DURATION", "ARCHIVE", "ERROR"}
+}
+
+func (d *Diagnostics) Rows() [][]string {
+	rows := make([][]string, 0, len(d.Servers))
+
+	for _, server := range d.Servers {
+		rows = append(rows, []string{server.Server, server.Status, server.Duration, d.Archive, server.Error})
+	}
+
+	return rows
+}
+
+// ClusterStatus is status of kubernetes nodes and kube-system pods.
+type ClusterStatus struct {
+	Nodes         []kube.NodeStatus `json:"nodes"         yaml:"nodes"`
+	UnhealthyPods []kube.PodStatus  `json:"unhealthyPods" yaml:"unhealthyPods"`
+}
+
+func (s *ClusterStatus) Header() []string {
+	return []string{"NAME", "STATUS", "ROLES", "SCHEDULABLE", "VERSION", "INTERNAL-IP", "AGE"}
+}
+
+func (s *ClusterStatus) Rows() [][]string {
+	rows := make([][]string, 0, len(s.Nodes))
+
+	for _, node := range s.Nodes {
+		rows = append(rows, []string{
+			node.Name,
+			node.Status,
+			node.Roles,
+			strconv.FormatBool(node.Schedulable),
+			node.Version,
+			node.InternalIP,
+			node.Age,
+		})
+	}
+
+	return rows
+}
+
+// GCResult is orphan server without kubernetes node or node without server.
+type GCResult struct {
+	Kind      string `json:"kind"                yaml:"kind"`
+	Name      string `json:"name"                yaml:"name"`
+	ServerID  string `json:"serverID,omitempty"  yaml:"serverID,omitempty"`
+	NodeGroup string `json:"nodeGroup,omitempty" yaml:"nodeGroup,omitempty"`
+	Age       string `json:"age"                 yaml:"age"`
+	Status    string `json:"status"              yaml:"status"`
+	Error     string `json:"error,omitempty"     yaml:"error,omitempty"`
+}
+
+type GCResults []GCResult
+
+// Failed returns number of orphans that were not deleted.
+func (r GCResults) Failed() int {
+	failed := 0
+
+	for _, result := range r {
+		if result.Status == GCStatusFailed {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (r GCResults) Header() []string {
+	return []string{"KIND", "NAME", "SERVER-ID", "NODE-GROUP", "AGE", "STATUS", "ERROR"}
+}
+
+func (r GCResults) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, result := range r {
+		rows = append(rows, []string{
+			result.Kind,
+			result.Name,
+			result.ServerID,
+			result.NodeGroup,
+			result.Age,
+			result.Status,
+			result.Error,
+		})
+	}
+
+	return rows
+}
+
+// CostEstimate is monthly cost of cluster.
+type CostEstimate struct {
+	Currency     string        `json:"currency"     yaml:"currency"`
+	PricingAt    string        `json:"pricingAt"    yaml:"pricingAt"`
+	ControlPlane pricing.Price `json:"controlPlane" yaml:"controlPlane"`
+	Current      pricing.Price `json:"current"      yaml:"current"`
+	WorstCase    pricing.Price `json:"worstCase"    yaml:"worstCase"`
+	Items        []CostItem    `json:"items"        yaml:"items"`
+}
+
+// CostItem is monthly cost of resources with same type in location.
+type CostItem struct {
+	Group    string        `json:"group"    yaml:"group"`
+	Resource string        `json:"resource" yaml:"resource"`
+	Type     string        `json:"type"     yaml:"type"`
+	Location string        `json:"location" yaml:"location"`
+	Count    int           `json:"count"    yaml:"count"`
+	Price    pricing.Price `json:"price"    yaml:"price"`
+	Total    pricing.Price `json:"total"    yaml:"total"`
+}
+
+func (e *CostEstimate) add(group, resource, resourceType, location string, count int, price pricing.Price) {
+	if count == 0 {
+		return
+	}
+
+	e.Items = append(e.Items, CostItem{
+		Group:    group,
+		Resource: resource,
+		Type:     resourceType,
+		Location: location,
+		Count:    count,
+		Price:    price,
+		Total:    pricing.Price{}.Add(price, count),
+	})
+}
+
+func (e *CostEstimate) total(group string) pricing.Price {
+	total := pricing.Price{}
+
+	for _, item := range e.Items {
+		if item.Group == group {
+			total = total.Add(item.Total, 1)
+		}
+	}
+
+	return total
+}
+
+func (e *CostEstimate) Header() []string {
+	return []string{"GROUP", "RESOURCE", "TYPE", "LOCATION", "COUNT", "MONTHLY", "TOTAL", "TOTAL-GROSS"}
+}
+
+func (e *CostEstimate) Rows() [][]string {
+	rows := make([][]string, 0, len(e.Items)+3) //nolint:mnd
+
+	formatPrice := func(price float64) string {
+		return strconv.FormatFloat(price, 'f', 2, 64) + " " + e.Currency
+	}
+
+	for _, item := range e.Items {
+		rows = append(rows, []string{
+			item.Group,
+			item.Resource,
+			item.Type,
+			item.Location,
+			strconv.Itoa(item.Count),
+			formatPrice(item.Price.Net),
+			formatPrice(item.Total.Net),
+			formatPrice(item.Total.Gross),
+		})
+	}
+
+	for _, total := range []struct {
+		name  string
+		price pricing.Price
+	}{
+		{"control plane", e.ControlPlane},
+		{"current", e.Current},
+		{"worst case", e.WorstCase},
+	} {
+		rows = append(rows, []string{
+			"total", total.name, "", "", "", "", formatPrice(total.price.Net), formatPrice(total.price.Gross),
+		})
+	}
+
+	return rows
+}
+
+// EtcdBackup is etcd snapshot saved to local file or backup storage.
+type EtcdBackup struct {
+	Server     string `json:"server"               yaml:"server"`
+	Snapshot   string `json:"snapshot"             yaml:"snapshot"`
+	Output     string `json:"output,omitempty"     yaml:"output,omitempty"`
+	StorageKey string `json:"storageKey,omitempty" yaml:"storageKey,omitempty"`
+	Size       int64  `json:"size"                 yaml:"size"`
+	SHA256     string `json:"sha256"               yaml:"sha256"`
+}
@@ -0,0 +1,199 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+type sftpConnection struct {
+	*sftp.Client
+	session *ssh.Session
+}
+
+func (c *sftpConnection) Close() error {
+	clientErr := c.Client.Close()
+	sessionErr := c.session.Close()
+
+	if clientErr != nil {
+		return errors.Wrap(clientErr, "error closing sftp client")
+	}
+
+	if sessionErr != nil {
+		return errors.Wrap(sessionErr, "error closing sftp session")
+	}
+
+	return nil
+}
+
+// newSFTPClient starts sftp server with sudo, to have access to all files on node.
+func newSFTPClient(client *ssh.Client) (*sftpConnection, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating session")
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting stdin")
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting stdout")
+	}
+
+	if err := session.Start(sftpServerCommand); err != nil {
+		return nil, errors.Wrap(err, "error starting sftp server")
+	}
+
+	sftpClient, err := sftp.NewClientPipe(stdout, stdin)
+	if err != nil {
+		_ = session.Close()
+
+		return nil, errors.Wrap(err, "error creating sftp client")
+	}
+
+	return &sftpConnection{
+		Client:  sftpClient,
+		session: session,
+	}, nil
+}
+
+// uploadFiles uploads files to directory on node, files are readable only by root.
+func (api *ApplicationAPI) uploadFiles(user string, ipAddress string, directory string, files map[string][]byte) error {
+	client, err := api.sshDial(user, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return err
+	}
+
+	defer sftpClient.Close()
+
+	if err := sftpClient.MkdirAll(directory); err != nil {
+		return errors.Wrapf(err, "error creating directory %s", directory)
+	}
+
+	for name, content := range files {
+		filePath := path.Join(directory, name)
+
+		log.Debugf("Uploading %s to %s", filePath, ipAddress)
+
+		if err := writeFile(sftpClient, filePath, bytes.NewReader(content), uploadFileMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uploadFile uploads large file to node without reading it to memory, file is readable only by root.
+func (api *ApplicationAPI) uploadFile(user string, ipAddress string, filePath string, r io.Reader) error {
+	client, err := api.sshDial(user, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return err
+	}
+
+	defer sftpClient.Close()
+
+	if err := sftpClient.MkdirAll(path.Dir(filePath)); err != nil {
+		return errors.Wrapf(err, "error creating directory %s", path.Dir(filePath))
+	}
+
+	log.Debugf("Uploading %s to %s", filePath, ipAddress)
+
+	return writeFile(sftpClient, filePath, r, uploadFileMode)
+}
+
+func writeFile(sftpClient *sftpConnection, filePath string, r io.Reader, mode fs.FileMode) error {
+	file, err := sftpClient.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", filePath)
+	}
+
+	if err := file.Chmod(mode); err != nil {
+		_ = file.Close()
+
+		return errors.Wrapf(err, "error changing mode %s", filePath)
+	}
+
+	if _, err := file.ReadFrom(r); err != nil {
+		_ = file.Close()
+
+		return errors.Wrapf(err, "error writing %s", filePath)
+	}
+
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "error closing %s", filePath)
+	}
+
+	return nil
+}
+
+// downloadFile writes file from node to writer, returns sha256 checksum of content.
+func (api *ApplicationAPI) downloadFile(user string, ipAddress string, filePath string, w io.Writer) (string, error) {
+	client, err := api.sshDial(user, ipAddress)
+	if err != nil {
+		return "", err
+	}
+
+	defer client.Close()
+
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return "", err
+	}
+
+	defer sftpClient.Close()
+
+	log.Debugf("Downloading %s from %s", filePath, ipAddress)
+
+	file, err := sftpClient.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "error opening %s", filePath)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(w, hash), file); err != nil {
+		return "", errors.Wrapf(err, "error reading %s", filePath)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+const (
+	defaultTerm   = "xterm-256color"
+	defaultWidth  = 80
+	defaultHeight = 24
+	ttySpeed      = 14400
+)
+
+type SSHOptions struct {
+	// server name
+	Node string
+	// ssh user, default is configured server user
+	User string
+	// local port forwards in format localPort:remoteHost:remotePort or localPort:remotePort
+	Forwards []string
+}
+
+// SSH opens interactive shell on cluster server, returns exit code of shell.
+func (api *ApplicationAPI) SSH(ctx context.Context, opts SSHOptions) (int, error) {
+	if len(opts.Node) == 0 {
+		return 0, errNodeNotSet
+	}
+
+	servers, err := api.selectAdhocServers(ctx, AdhocOptions{
+		Selector: adhocSelectorName + "^" + regexp.QuoteMeta(opts.Node) + "$",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(servers) == 0 {
+		return 0, errors.Wrap(errNoServersFound, opts.Node)
+	}
+
+	serverIP := servers[0].PublicNet.IPv4.IP.String()
+
+	user := api.sshRootUser
+	if len(opts.User) > 0 {
+		user = opts.User
+	}
+
+	log.Infof("Connecting to %s@%s (%s)...", user, opts.Node, serverIP)
+
+	client, err := api.sshDial(user, serverIP)
+	if err != nil {
+		return 0, err
+	}
+
+	defer client.Close()
+
+	for _, forward := range opts.Forwards {
+		if err := forwardPort(ctx, client, forward); err != nil {
+			return 0, err
+		}
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating session")
+	}
+
+	defer session.Close()
+
+	return runShell(session)
+}
+
+// runShell runs shell with terminal attached to stdin, stdout and stderr.
+func runShell(session *ssh.Session) (int, error) {
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd()) //nolint:gosec
+
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = defaultWidth, defaultHeight
+		}
+
+		termType := os.Getenv("TERM")
+		if len(termType) == 0 {
+			termType = defaultTerm
+		}
+
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: ttySpeed,
+			ssh.TTY_OP_OSPEED: ttySpeed,
+		}
+
+		if err := session.RequestPty(termType, height, width, modes); err != nil {
+			return 0, errors.Wrap(err, "error requesting pty")
+		}
+
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return 0, errors.Wrap(err, "error setting terminal to raw mode")
+		}
+
+		defer term.Restore(fd, state) //nolint:errcheck
+
+		stop := watchWindowSize(fd, session)
+		defer stop()
+	}
+
+	if err := session.Shell(); err != nil {
+		return 0, errors.Wrap(err, "error starting shell")
+	}
+
+	err := session.Wait()
+
+	var exitError *ssh.ExitError
+
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitError):
+		return exitError.ExitStatus(), nil
+	default:
+		return 0, errors.Wrap(err, "error in shell")
+	}
+}
+
+// watchWindowSize sends terminal size changes to server.
+func watchWindowSize(fd int, session *ssh.Session) func() {
+	sigwinch := make(chan os.Signal, 1)
+	signal.Notify(sigwinch, syscall.SIGWINCH)
+
+	go func() {
+		for range sigwinch {
+			if width, height, err := term.GetSize(fd); err == nil {
+				_ = session.WindowChange(height, width)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigwinch)
+		close(sigwinch)
+	}
+}
+
+// forwardPort forwards connections from local port to remote address through ssh connection.
+func forwardPort(ctx context.Context, client *ssh.Client, forward string) error {
+	localPort, remoteAddress, err := parseForward(forward)
+	if err != nil {
+		return err
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", net.JoinHostPort("127.0.0.1", localPort))
+	if err != nil {
+		return errors.Wrapf(err, "error listening on port %s", localPort)
+	}
+
+	go func() {
+		_ = client.Wait()
+		_ = listener.Close()
+	}()
+
+	log.Infof("Forwarding 127.0.0.1:%s -> %s", localPort, remoteAddress)
+
+	go func() {
+		for {
+			local, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer local.Close()
+
+				remote, err := client.Dial("tcp", remoteAddress)
+				if err != nil {
+					log.WithError(err).Warnf("error connecting to %s", remoteAddress)
+
+					return
+				}
+
+				defer remote.Close()
+
+				done := make(chan struct{}, 2) //nolint:mnd
+
+				go func() {
+					_, _ = io.Copy(remote, local)
+					done <- struct{}{}
+				}()
+
+				go func() {
+					_, _ = io.Copy(local, remote)
+					done <- struct{}{}
+				}()
+
+				<-done
+			}()
+		}
+	}()
+
+	return nil
+}
+
+// parseForward returns local port and remote address from localPort:remoteHost:remotePort.
+func parseForward(forward string) (string, string, error) {
+	parts := strings.Split(forward, ":")
+
+	switch len(parts) {
+	case 2: //nolint:mnd
+		parts = []string{parts[0], "127.0.0.1", parts[1]}
+	case 3: //nolint:mnd
+	default:
+		return "", "", errors.Wrap(errInvalidForward, forward)
+	}
+
+	for _, port := range []string{parts[0], parts[2]} {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", "", errors.Wrap(errInvalidForward, forward)
+		}
+	}
+
+	return parts[0], net.JoinHostPort(parts[1], parts[2]), nil
+}
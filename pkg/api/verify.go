@@ -0,0 +1,225 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kube"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	verifyClusterCommand = "/root/scripts/verify-cluster.sh"
+	verifyInterval       = 10 * time.Second
+	helmReleaseDeployed  = "deployed"
+	etcdMemberStarted    = "started"
+)
+
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+}
+
+// verifyCluster waits until cluster is healthy, each check is retried until timeout.
+func (api *ApplicationAPI) verifyCluster(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"master nodes ready", api.verifyMastersReady},
+		{"etcd members", api.verifyEtcdMembers},
+		{"kube-system pods", api.verifyKubeSystemPods},
+		{"helm releases", api.verifyHelmReleases},
+		{"load balancer targets", api.verifyLoadBalancerTargets},
+	}
+
+	for _, check := range checks {
+		log.Infof("Verifying %s...", check.name)
+
+		lastErr := errClusterNotHealthy
+
+		err := wait.PollUntilContextCancel(ctx, verifyInterval, true, func(ctx context.Context) (bool, error) {
+			if lastErr = check.check(ctx); lastErr != nil {
+				log.WithError(lastErr).Debugf("%s not verified", check.name)
+
+				return false, nil
+			}
+
+			return true, nil
+		})
+		if err != nil {
+			return errors.Wrapf(lastErr, "verification of %s failed in %s", check.name, timeout)
+		}
+	}
+
+	log.Info("Cluster verified")
+
+	return nil
+}
+
+func (api *ApplicationAPI) verifyMastersReady(ctx context.Context) error {
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	ready, err := kubeClient.ReadyNodes(ctx, kube.LabelControlPlane)
+	if err != nil {
+		return errors.Wrap(err, "error getting nodes")
+	}
+
+	if ready != config.Get().MasterCount {
+		return errors.Wrapf(errClusterNotHealthy, "%d of %d master nodes ready", ready, config.Get().MasterCount)
+	}
+
+	return nil
+}
+
+func (api *ApplicationAPI) verifyEtcdMembers(ctx context.Context) error {
+	stdout, err := api.execOnFirstMaster(ctx, verifyClusterCommand+" etcd-members")
+	if err != nil {
+		return err
+	}
+
+	started := 0
+
+	// id, status, name, peer urls, client urls, is learner
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		fields := strings.Split(line, ", ")
+		if len(fields) > 1 && fields[1] == etcdMemberStarted {
+			started++
+		}
+	}
+
+	if started != config.Get().MasterCount {
+		return errors.Wrapf(errClusterNotHealthy, "%d of %d etcd members started", started, config.Get().MasterCount)
+	}
+
+	return nil
+}
+
+func (api *ApplicationAPI) verifyKubeSystemPods(ctx context.Context) error {
+	kubeClient, err := api.kubeClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	pods, err := kubeClient.UnhealthyPods(ctx, metav1.NamespaceSystem)
+	if err != nil {
+		return errors.Wrap(err, "error getting pods")
+	}
+
+	if len(pods) > 0 {
+		names := make([]string, 0, len(pods))
+
+		for _, pod := range pods {
+			names = append(names, fmt.Sprintf("%s(%s)", pod.Name, pod.Phase))
+		}
+
+		return errors.Wrapf(errClusterNotHealthy, "pods not ready: %s", strings.Join(names, ","))
+	}
+
+	return nil
+}
+
+func (api *ApplicationAPI) verifyHelmReleases(ctx context.Context) error {
+	stdout, err := api.execOnFirstMaster(ctx, verifyClusterCommand+" helm-releases")
+	if err != nil {
+		return err
+	}
+
+	releases := make([]helmRelease, 0)
+
+	if err := json.Unmarshal([]byte(stdout), &releases); err != nil {
+		return errors.Wrap(err, "error parsing helm releases")
+	}
+
+	if len(releases) == 0 {
+		return errors.Wrap(errClusterNotHealthy, "no helm releases")
+	}
+
+	for _, release := range releases {
+		if release.Status != helmReleaseDeployed {
+			return errors.Wrapf(errClusterNotHealthy, "helm release %s/%s is %s",
+				release.Namespace, release.Name, release.Status)
+		}
+	}
+
+	return nil
+}
+
+func (api *ApplicationAPI) verifyLoadBalancerTargets(ctx context.Context) error {
+	loadBalancer, _, err := api.hcloudClient.LoadBalancer.Get(ctx, config.Get().ClusterName)
+	if err != nil {
+		return errors.Wrap(err, "error getting loadbalancer")
+	}
+
+	if loadBalancer == nil {
+		return errors.Wrap(errClusterNotHealthy, "loadbalancer not found")
+	}
+
+	healthy := 0
+
+	for _, target := range loadBalancer.Targets {
+		if target.Type != hcloud.LoadBalancerTargetTypeServer || len(target.HealthStatus) == 0 {
+			continue
+		}
+
+		targetHealthy := true
+
+		for _, status := range target.HealthStatus {
+			if status.Status != hcloud.LoadBalancerTargetHealthStatusStatusHealthy {
+				targetHealthy = false
+			}
+		}
+
+		if targetHealthy {
+			healthy++
+		}
+	}
+
+	if healthy < config.Get().MasterCount {
+		return errors.Wrapf(errClusterNotHealthy, "%d of %d loadbalancer targets healthy", healthy, config.Get().MasterCount)
+	}
+
+	return nil
+}
+
+// execOnFirstMaster executes command on first master node without streaming, returns stdout.
+func (api *ApplicationAPI) execOnFirstMaster(ctx context.Context, command string) (string, error) {
+	serverIP, err := api.waitForServer(ctx, fmt.Sprintf(config.Get().MasterServers.NamePattern, 1))
+	if err != nil {
+		return "", err
+	}
+
+	stdout, stderr, err := api.execCommandQuiet(serverIP, command)
+	if err != nil {
+		return "", errors.Wrap(err, stderr)
+	}
+
+	return stdout, nil
+}
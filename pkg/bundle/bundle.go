@@ -0,0 +1,163 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	fileMode = 0o644
+	dirMode  = 0o755
+)
+
+// Bundle is a tar.gz archive of scripts with sha256 checksum.
+type Bundle struct {
+	Data     []byte
+	Checksum string
+}
+
+// New creates reproducible tar.gz archive from filesystem layers,
+// files from next layer override files with same path from previous layers,
+// same files always produce same checksum.
+func New(layers ...fs.FS) (*Bundle, error) {
+	return NewWithTemplates(Templates{}, layers...)
+}
+
+// NewWithTemplates creates archive like New, template files are rendered after layers are merged.
+func NewWithTemplates(templates Templates, layers ...fs.FS) (*Bundle, error) {
+	files, err := merge(layers...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading files")
+	}
+
+	if err := templates.render(files); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	// parent directory always sorted before its files
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&b)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, path := range paths {
+		content := files[path]
+
+		if content == nil {
+			if err := tarWriter.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     path + "/",
+				Mode:     dirMode,
+			}); err != nil {
+				return nil, errors.Wrapf(err, "error writing header %s", path)
+			}
+
+			continue
+		}
+
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path,
+			Mode:     fileMode,
+			Size:     int64(len(content)),
+		}); err != nil {
+			return nil, errors.Wrapf(err, "error writing header %s", path)
+		}
+
+		if _, err := tarWriter.Write(content); err != nil {
+			return nil, errors.Wrapf(err, "error writing %s", path)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "error closing tar")
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "error closing gzip")
+	}
+
+	checksum := sha256.Sum256(b.Bytes())
+
+	return &Bundle{
+		Data:     b.Bytes(),
+		Checksum: hex.EncodeToString(checksum[:]),
+	}, nil
+}
+
+// merge returns content of all files from layers, directories have nil content.
+func merge(layers ...fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for _, layer := range layers {
+		err := fs.WalkDir(layer, ".", func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if path == "." {
+				return nil
+			}
+
+			if entry.IsDir() {
+				if content, ok := files[path]; ok && content != nil {
+					return errors.Errorf("%s is a file in previous layer", path)
+				}
+
+				files[path] = nil
+
+				return nil
+			}
+
+			if content, ok := files[path]; ok && content == nil {
+				return errors.Errorf("%s is a directory in previous layer", path)
+			}
+
+			content, err := fs.ReadFile(layer, path)
+			if err != nil {
+				return errors.Wrapf(err, "error reading %s", path)
+			}
+
+			// empty file must not be treated as directory
+			if content == nil {
+				content = []byte{}
+			}
+
+			files[path] = content
+
+			return nil
+		})
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/bundle"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/scripts"
+)
+
+func TestBundle(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"init-master.sh":         {Data: []byte("#!/bin/bash")},
+		"chart/Chart.yaml":       {Data: []byte("name: test")},
+		"chart/templates/a.yaml": {Data: []byte("a: b")},
+	}
+
+	first, err := bundle.New(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := bundle.New(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Checksum != second.Checksum {
+		t.Fatal("bundle is not reproducible")
+	}
+
+	files := readBundle(t, first)
+
+	if files["chart/templates/a.yaml"] != "a: b" {
+		t.Fatalf("unexpected content %+v", files)
+	}
+}
+
+func TestBundleOverlay(t *testing.T) {
+	t.Parallel()
+
+	embedded := fstest.MapFS{
+		"init-master.sh":   {Data: []byte("embedded")},
+		"post-install.sh":  {Data: []byte("embedded")},
+		"chart/Chart.yaml": {Data: []byte("name: test")},
+	}
+
+	override := fstest.MapFS{
+		"post-install.sh":  {Data: []byte("override")},
+		"chart/extra.yaml": {Data: []byte("extra")},
+	}
+
+	result, err := bundle.New(embedded, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readBundle(t, result)
+
+	if files["init-master.sh"] != "embedded" {
+		t.Fatalf("embedded file must be kept %+v", files)
+	}
+
+	if files["post-install.sh"] != "override" {
+		t.Fatalf("file must be overridden %+v", files)
+	}
+
+	if files["chart/extra.yaml"] != "extra" {
+		t.Fatalf("new file must be added %+v", files)
+	}
+
+	withoutOverride, err := bundle.New(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if withoutOverride.Checksum == result.Checksum {
+		t.Fatal("checksum must be changed")
+	}
+}
+
+func TestBundleOverlayConflict(t *testing.T) {
+	t.Parallel()
+
+	embedded := fstest.MapFS{
+		"chart/Chart.yaml": {Data: []byte("name: test")},
+	}
+
+	override := fstest.MapFS{
+		"chart": {Data: []byte("file")},
+	}
+
+	if _, err := bundle.New(embedded, override); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func TestEmbeddedScripts(t *testing.T) {
+	t.Parallel()
+
+	result, err := bundle.New(scripts.FS())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Checksum) != 64 {
+		t.Fatal("invalid checksum")
+	}
+}
+
+func TestBundleTemplates(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"common-install.sh": {Data: []byte("VERSION={{ .Values.version }}\n{{ toYaml .Values.kubelet }}")},
+		"post-install.sh":   {Data: []byte("{{ not templated }}")},
+		"etcd-backup.timer": {Data: []byte("{{ if .Values.backup }}OnUnitActiveSec=1h{{ end }}")},
+	}
+
+	result, err := bundle.NewWithTemplates(bundle.Templates{
+		Files: []string{"common-install.sh", "etcd-backup.timer"},
+		Values: map[string]interface{}{
+			"version": "1.30",
+			"kubelet": map[string]interface{}{"maxPods": 110},
+		},
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readBundle(t, result)
+
+	if files["common-install.sh"] != "VERSION=1.30\nmaxPods: 110" {
+		t.Fatalf("unexpected content %q", files["common-install.sh"])
+	}
+
+	if content, ok := files["etcd-backup.timer"]; !ok || len(content) > 0 {
+		t.Fatalf("empty template must be a file %+v", files)
+	}
+
+	if files["post-install.sh"] != "{{ not templated }}" {
+		t.Fatalf("unexpected content %q", files["post-install.sh"])
+	}
+
+	if _, err := bundle.NewWithTemplates(bundle.Templates{Files: []string{"not-found.sh"}}, fsys); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func readBundle(t *testing.T, result *bundle.Bundle) map[string]string {
+	t.Helper()
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+
+	tarReader := tar.NewReader(gzipReader)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[header.Name] = string(content)
+	}
+
+	return files
+}
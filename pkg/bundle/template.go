@@ -0,0 +1,78 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const yamlIndent = 2
+
+// Templates are files in bundle that are rendered with go templates,
+// values are available in templates as .Values.
+type Templates struct {
+	Files  []string
+	Values map[string]interface{}
+}
+
+func (t Templates) render(files map[string][]byte) error {
+	data := map[string]interface{}{
+		"Values": t.Values,
+	}
+
+	for _, path := range t.Files {
+		content, ok := files[path]
+		if !ok || content == nil {
+			return errors.Errorf("template %s not found", path)
+		}
+
+		tmpl, err := template.New(path).Funcs(template.FuncMap{"toYaml": toYaml}).Parse(string(content))
+		if err != nil {
+			return errors.Wrapf(err, "error parsing template %s", path)
+		}
+
+		var b bytes.Buffer
+
+		if err := tmpl.Execute(&b, data); err != nil {
+			return errors.Wrapf(err, "error executing template %s", path)
+		}
+
+		// nil content is a directory, empty template must stay a file
+		files[path] = append([]byte{}, b.Bytes()...)
+	}
+
+	return nil
+}
+
+// toYaml returns value as yaml without trailing newline.
+func toYaml(value interface{}) (string, error) {
+	var b bytes.Buffer
+
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(yamlIndent)
+
+	if err := encoder.Encode(value); err != nil {
+		return "", errors.Wrap(err, "error encoding yaml")
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", errors.Wrap(err, "error encoding yaml")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
@@ -21,35 +21,73 @@ import (
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
+// Deprecated: scripts are embedded in binary, use scriptsDir to override them.
 type masterServersInitParams struct {
 	TarGz  string
 	Folder string
 }
 
 type cliArgs struct {
-	LogLevel                   *string
-	ConfigPath                 *string
-	SaveConfigPath             *string
-	Action                     *string
-	AdhocCommand               *string
-	AdhocCopyNewFile           *bool
-	AdhocMasters               *bool
-	AdhocWorkers               *bool
-	AdhocUser                  *string
-	UpgradeControlPlaneVersion *string
-	CreateFirewallControlPlane *bool
-	CreateFirewallWorkers      *bool
+	LogLevel                      *string
+	LogStream                     *bool
+	LogRunDir                     *string
+	ConfigPath                    *string
+	ConfigSecretsPath             *string
+	SaveConfigPath                *string
+	Action                        *string
+	Output                        *string
+	CreateVerifyTimeout           *time.Duration
+	AdhocCommand                  *string
+	AdhocCopyNewFile              *bool
+	AdhocMasters                  *bool
+	AdhocWorkers                  *bool
+	AdhocUser                     *string
+	AdhocSelector                 *string
+	AdhocParallel                 *int
+	AdhocTimeout                  *time.Duration
+	AdhocFailFast                 *bool
+	UpgradeControlPlaneVersion    *string
+	UpgradeControlPlaneSkipBackup *bool
+	CreateFirewallControlPlane    *bool
+	CreateFirewallWorkers         *bool
+	RestoreEtcdSnapshot           *string
+	BackupEtcdOutput              *string
+	BackupEtcdKeep                *int
+	IssueKubeconfigUser           *string
+	IssueKubeconfigGroup          *string
+	IssueKubeconfigTTL            *time.Duration
+	IssueKubeconfigOutput         *string
+	SSHNode                       *string
+	SSHUser                       *string
+	SSHForward                    *string
+	CopySource                    *string
+	CopyDestination               *string
+	CopySelector                  *string
+	CopyParallel                  *int
+	CopyMode                      *string
+	CollectDiagnosticsSelector    *string
+	CollectDiagnosticsOutput      *string
+	CollectDiagnosticsSince       *time.Duration
+	DrainNode                     *string
+	DrainTimeout                  *time.Duration
+	DrainUncordon                 *bool
+	GCMinAge                      *time.Duration
+	GCDelete                      *bool
+	EstimateCostPricing           *string
+	EstimateCostSavePricing       *string
 }
 
 type masterServers struct {
@@ -59,15 +97,43 @@ type masterServers struct {
 	Labels             map[string]string
 	WaitTimeInRetry    time.Duration
 	RetryTimeLimit     int
-	ServersInitParams  masterServersInitParams
+	// Deprecated: ignored, scripts are embedded in binary
+	ServersInitParams masterServersInitParams `yaml:",omitempty"`
 }
 
 type emptyStruct struct{}
 
+// LoadBalancerHealthCheck is a health check of load balancer service,
+// empty values in extra services are taken from kubernetes API health check.
+type LoadBalancerHealthCheck struct {
+	Protocol    hcloud.LoadBalancerServiceProtocol
+	Port        int
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	Path        string
+	StatusCodes []string
+	TLS         bool
+}
+
+// LoadBalancerService is an extra service of load balancer, for example ingress 80/443 to NodePorts on workers.
+type LoadBalancerService struct {
+	Protocol        hcloud.LoadBalancerServiceProtocol
+	ListenPort      int
+	DestinationPort int
+	ProxyProtocol   bool
+	HealthCheck     LoadBalancerHealthCheck
+}
+
 type masterLoadBalancer struct {
 	LoadBalancerType string
 	ListenPort       int
 	DestinationPort  int
+	Algorithm        hcloud.LoadBalancerAlgorithmType
+	HealthCheck      LoadBalancerHealthCheck
+	Services         []LoadBalancerService
+	// additional targets, for example hcloud/node-group to route traffic to workers
+	LabelSelectorTargets []string
 }
 
 type serverComponentContainerd struct {
@@ -85,6 +151,8 @@ type serverComponentUbuntu struct {
 	Version      string
 	UserName     string
 	Architecture hcloud.Architecture
+	// snapshot created with build-image action, used instead of Version
+	SnapshotID int64
 }
 type serverComponents struct {
 	Ubuntu     serverComponentUbuntu
@@ -93,7 +161,26 @@ type serverComponents struct {
 	Containerd serverComponentContainerd
 }
 
-type clusterAutoscalingGroup struct {
+// etcd snapshots storage, S3 compatible.
+type backup struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string // default is cluster name
+	AccessKey string
+	SecretKey string
+	// number of snapshots to keep in bucket
+	Retention int
+	// interval of scheduled snapshots on master nodes
+	Schedule string
+}
+
+func (b backup) Enabled() bool {
+	return len(b.Bucket) > 0
+}
+
+// ClusterAutoscalingGroup is node group of cluster-autoscaler.
+type ClusterAutoscalingGroup struct {
 	Name         string `yaml:"name"`
 	MinSize      int    `yaml:"minSize"`
 	MaxSize      int    `yaml:"maxSize"`
@@ -101,21 +188,8 @@ type clusterAutoscalingGroup struct {
 	Region       string `yaml:"region"`
 }
 
-func getDefaultMasterServersInitParams() masterServersInitParams {
-	branch := "main"
-
-	if b := os.Getenv("HCLOUD_CTL_BRANCH"); len(b) > 0 {
-		branch = b
-	}
-
-	return masterServersInitParams{
-		TarGz:  fmt.Sprintf("https://github.com/maksim-paskal/hcloud-k8s-ctl/archive/refs/heads/%s.tar.gz", branch),
-		Folder: "hcloud-k8s-ctl-" + branch,
-	}
-}
-
 func getDefaultClusterAutoscaler() map[interface{}]interface{} {
-	result := make([]*clusterAutoscalingGroup, 0)
+	result := make([]*ClusterAutoscalingGroup, 0)
 
 	defaultLocations := []string{
 		hcloudLocationEUFalkenstein,
@@ -140,7 +214,7 @@ func getDefaultClusterAutoscaler() map[interface{}]interface{} {
 
 	for _, location := range defaultLocations {
 		for _, server := range defaultServers {
-			result = append(result, &clusterAutoscalingGroup{
+			result = append(result, &ClusterAutoscalingGroup{
 				Name:         fmt.Sprintf("%s-%s", strings.TrimSpace(server), strings.TrimSpace(location)),
 				MinSize:      0,
 				MaxSize:      workersMaxSize,
@@ -161,12 +235,14 @@ var config = Type{}
 type Type struct {
 	ClusterName        string             `yaml:"clusterName"`
 	KubeConfigPath     string             `yaml:"kubeConfigPath"`
+	KubeConfigMerge    bool               `yaml:"kubeConfigMerge"` // merge into existing kubeconfig
 	HetznerToken       string             `yaml:"hetznerToken"`
 	ServerComponents   serverComponents   `yaml:"serverComponents"`
 	IPRange            string             `yaml:"ipRange"`
 	IPRangeSubnet      string             `yaml:"ipRangeSubnet"`
 	SSHPrivateKey      string             `yaml:"sshPrivateKey"`
 	SSHPublicKey       string             `yaml:"sshPublicKey"`
+	SSHKnownHosts      string             `yaml:"sshKnownHosts"` // pinned host keys of servers
 	MasterCount        int                `yaml:"masterCount"`
 	NetworkZone        hcloud.NetworkZone `yaml:"networkZone"`
 	Location           string             `yaml:"location"`
@@ -177,6 +253,10 @@ type Type struct {
 	Deployments        interface{}        `yaml:"deployments"` // values.yaml in chart
 	PreStartScript     string             `yaml:"preStartScript"`
 	PostStartScript    string             `yaml:"postStartScript"`
+	ScriptsDir         string             `yaml:"scriptsDir"` // overrides embedded scripts
+	Hooks              Hooks              `yaml:"hooks"`
+	Backup             backup             `yaml:"backup"`
+	SensitiveKeys      []string           `yaml:"sensitiveKeys"` // masked in logs
 
 	Kubelet map[interface{}]interface{} `yaml:"kubelet,omitempty"`
 
@@ -192,23 +272,57 @@ type Type struct {
 
 //nolint:gochecknoglobals
 var cliArguments = cliArgs{
-	LogLevel:                   flag.String("log.level", "INFO", "logging level"),
-	SaveConfigPath:             flag.String("save-config-path", "", "save full config path"),
-	ConfigPath:                 flag.String("config", envDefault("CONFIG", "config.yaml"), "config path"),
-	Action:                     flag.String("action", "", "create|delete|list-configurations|patch-cluster|adhoc|upgrade-controlplane|create-firewall"), //nolint:lll
-	AdhocCommand:               flag.String("adhoc.command", "", "command to adhoc action"),
-	AdhocCopyNewFile:           flag.Bool("adhoc.copynewfile", false, "copy new files to adhoc action"),
-	AdhocMasters:               flag.Bool("adhoc.master", false, "run adhoc also on master servers"),
-	AdhocWorkers:               flag.Bool("adhoc.workers", true, "run adhoc also on workers servers"),
-	AdhocUser:                  flag.String("adhoc.user", "", "ssh user for adhoc action"),
-	UpgradeControlPlaneVersion: flag.String("upgrade-controlplane.version", "", "controlplane version to upgrade"),
-	CreateFirewallControlPlane: flag.Bool("create-firewall.controlplane", false, "create firewall for controlplane"),
-	CreateFirewallWorkers:      flag.Bool("create-firewall.workers", false, "create firewall for workers"),
+	LogLevel:                      flag.String("log.level", "INFO", "logging level"),
+	LogStream:                     flag.Bool("log.stream", true, "stream servers output to stderr"),
+	LogRunDir:                     flag.String("log.run-dir", "", "save servers output to log files in directory"),
+	SaveConfigPath:                flag.String("save-config-path", "", "save full config path"),
+	ConfigPath:                    flag.String("config", envDefault("CONFIG", "config.yaml"), "config path"),
+	ConfigSecretsPath:             flag.String("config.secrets", envDefault("CONFIG_SECRETS", ""), "secrets config path"),
+	Action:                        flag.String("action", "", "create|delete|list-configurations|patch-cluster|adhoc|upgrade-controlplane|create-firewall|update-loadbalancer|build-image|backup-etcd|restore-etcd|get-kubeconfig|issue-kubeconfig|ssh|copy|collect-diagnostics|status|drain|gc|estimate-cost"), //nolint:lll
+	Output:                        flag.String("output", "", "print action result to stdout in format json|yaml|table"),
+	CreateVerifyTimeout:           flag.Duration("create.verify-timeout", createVerifyTimeout, "verify timeout, 0 skips"),
+	AdhocCommand:                  flag.String("adhoc.command", "", "command to adhoc action"),
+	AdhocCopyNewFile:              flag.Bool("adhoc.copynewfile", false, "copy new files to adhoc action"),
+	AdhocMasters:                  flag.Bool("adhoc.master", false, "run adhoc also on master servers"),
+	AdhocWorkers:                  flag.Bool("adhoc.workers", true, "run adhoc also on workers servers"),
+	AdhocUser:                     flag.String("adhoc.user", "", "ssh user for adhoc action"),
+	AdhocSelector:                 flag.String("adhoc.selector", "", "node-group:<name>, name:<regexp> or label selector"),
+	AdhocParallel:                 flag.Int("adhoc.parallel", 0, "servers executing adhoc at same time, 0 is unlimited"),
+	AdhocTimeout:                  flag.Duration("adhoc.timeout", 0, "adhoc command timeout on each server"),
+	AdhocFailFast:                 flag.Bool("adhoc.fail-fast", false, "cancel adhoc on other servers after first failure"),
+	UpgradeControlPlaneVersion:    flag.String("upgrade-controlplane.version", "", "controlplane version to upgrade"),
+	UpgradeControlPlaneSkipBackup: flag.Bool("upgrade-controlplane.skip-backup", false, "upgrade without etcd backup"),
+	CreateFirewallControlPlane:    flag.Bool("create-firewall.controlplane", false, "create firewall for controlplane"),
+	CreateFirewallWorkers:         flag.Bool("create-firewall.workers", false, "create firewall for workers"),
+	BackupEtcdOutput:              flag.String("backup-etcd.output", "", "save etcd snapshot to local file"),
+	BackupEtcdKeep:                flag.Int("backup-etcd.keep", backupEtcdKeep, "etcd snapshots to keep on master node"),
+	RestoreEtcdSnapshot:           flag.String("restore-etcd.snapshot", "", "snapshot to restore: local path, s3://bucket/key or latest"), //nolint:lll
+	IssueKubeconfigUser:           flag.String("issue-kubeconfig.user", "", "user name in issued kubeconfig"),
+	IssueKubeconfigGroup:          flag.String("issue-kubeconfig.group", "", "comma separated user groups in issued kubeconfig"),
+	IssueKubeconfigTTL:            flag.Duration("issue-kubeconfig.ttl", issueKubeconfigTTL, "issued kubeconfig certificate lifetime"),
+	IssueKubeconfigOutput:         flag.String("issue-kubeconfig.output", "", "issued kubeconfig path, default <clusterName>-<user>.kubeconfig"), //nolint:lll
+	SSHNode:                       flag.String("ssh.node", "", "server name to open shell"),
+	SSHUser:                       flag.String("ssh.user", "", "ssh user, default is serverComponents.ubuntu.username"),
+	SSHForward:                    flag.String("ssh.forward", "", "comma separated port forwards localPort:remoteHost:remotePort"), //nolint:lll
+	CopySource:                    flag.String("copy.src", "", "local file or :/path/on/node"),
+	CopyDestination:               flag.String("copy.dst", "", "local directory or :/path/on/node"),
+	CopySelector:                  flag.String("copy.selector", "", "node-group:<name>, name:<regexp> or label selector"),
+	CopyParallel:                  flag.Int("copy.parallel", 0, "servers copying at same time, 0 is unlimited"),
+	CopyMode:                      flag.String("copy.mode", "0644", "mode of uploaded file"),
+	CollectDiagnosticsSelector:    flag.String("collect-diagnostics.selector", "", "node selector like in adhoc"),
+	CollectDiagnosticsOutput:      flag.String("collect-diagnostics.output", "", "tar.gz archive path"),
+	CollectDiagnosticsSince:       flag.Duration("collect-diagnostics.since", collectDiagnosticsSince, "journal period"),
+	DrainNode:                     flag.String("drain.node", "", "node name to drain"),
+	DrainTimeout:                  flag.Duration("drain.timeout", drainTimeout, "timeout of pods eviction"),
+	DrainUncordon:                 flag.Bool("drain.uncordon", false, "uncordon node instead of drain"),
+	GCMinAge:                      flag.Duration("gc.min-age", gcMinAge, "minimal age of orphan servers"),
+	GCDelete:                      flag.Bool("gc.delete", false, "delete orphan servers and nodes"),
+	EstimateCostPricing:           flag.String("estimate-cost.pricing", "", "pricing snapshot path"),
+	EstimateCostSavePricing:       flag.String("estimate-cost.save-pricing", "", "save pricing snapshot to path"),
 }
 
-func SetServersInitParams() {
-	config.MasterServers.ServersInitParams = getDefaultMasterServersInitParams()
-}
+// Deprecated: scripts are embedded in binary, SetServersInitParams does nothing.
+func SetServersInitParams() {}
 
 func defaultConfig() Type {
 	privateKey := "~/.ssh/id_rsa"
@@ -245,6 +359,7 @@ func defaultConfig() Type {
 		KubeConfigPath: kubeConfigPath,
 		SSHPrivateKey:  privateKey,
 		SSHPublicKey:   privateKey + ".pub",
+		SSHKnownHosts:  "~/.ssh/hcloud-k8s-ctl_known_hosts",
 		MasterCount:    masterServersCount,
 		CliArgs:        cliArguments,
 		MasterServers: masterServers{
@@ -254,12 +369,26 @@ func defaultConfig() Type {
 			Labels:             serverLabels,
 			WaitTimeInRetry:    waitTimeInRetry,
 			RetryTimeLimit:     retryTimeLimit,
-			ServersInitParams:  getDefaultMasterServersInitParams(),
 		},
 		MasterLoadBalancer: masterLoadBalancer{
 			LoadBalancerType: "lb11",
 			ListenPort:       loadBalancerDefaultPort,
 			DestinationPort:  loadBalancerDefaultPort,
+			Algorithm:        hcloud.LoadBalancerAlgorithmTypeRoundRobin,
+			HealthCheck: LoadBalancerHealthCheck{
+				Protocol:    hcloud.LoadBalancerServiceProtocolHTTP,
+				Interval:    loadBalancerHealthCheckInterval,
+				Timeout:     loadBalancerHealthCheckTimeout,
+				Retries:     loadBalancerHealthCheckRetries,
+				Path:        "/healthz",
+				StatusCodes: []string{"2??"},
+				TLS:         true,
+			},
+		},
+		Backup: backup{
+			Region:    "us-east-1",
+			Retention: backupRetention,
+			Schedule:  backupSchedule,
 		},
 		Deployments:       emptyStruct{},
 		ClusterAutoscaler: getDefaultClusterAutoscaler(),
@@ -273,6 +402,13 @@ func Load() error { //nolint: cyclop
 		return errors.Wrap(err, "failed to read config file")
 	}
 
+	if secretsPath := *cliArguments.ConfigSecretsPath; len(secretsPath) > 0 {
+		configByte, err = loadSecretsOverlay(configByte, secretsPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to load secrets overlay")
+		}
+	}
+
 	config = defaultConfig()
 
 	if len(config.HetznerToken) == 0 {
@@ -293,6 +429,18 @@ func Load() error { //nolint: cyclop
 		return errors.Wrap(err, "failed to unmarshal config file")
 	}
 
+	if config.MasterServers.ServersInitParams != (masterServersInitParams{}) {
+		log.Warn("masterServers.serversInitParams is deprecated and ignored, scripts are embedded in binary, use scriptsDir to override them") //nolint:lll
+	}
+
+	if len(config.Backup.Prefix) == 0 {
+		config.Backup.Prefix = config.ClusterName
+	}
+
+	if err = config.resolveSecrets(); err != nil {
+		return errors.Wrap(err, "failed to resolve secrets")
+	}
+
 	config.KubeConfigPath, err = expand(config.KubeConfigPath)
 	if err != nil {
 		return errors.Wrap(err, "failed to expand kube config path")
@@ -308,6 +456,16 @@ func Load() error { //nolint: cyclop
 		return errors.Wrap(err, "failed to expand ssh public key path")
 	}
 
+	config.SSHKnownHosts, err = expand(config.SSHKnownHosts)
+	if err != nil {
+		return errors.Wrap(err, "failed to expand ssh known hosts path")
+	}
+
+	config.ScriptsDir, err = expand(config.ScriptsDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to expand scripts dir path")
+	}
+
 	_, _, err = net.ParseCIDR(config.IPRange)
 	if err != nil {
 		return errors.Wrap(err, "failed to parse ip range")
@@ -318,27 +476,167 @@ func Load() error { //nolint: cyclop
 		return errors.Wrap(err, "failed to parse ip range subnet")
 	}
 
+	// validate hooks templates
+	if _, err = RenderHooks(); err != nil {
+		return errors.Wrap(err, "failed to render hooks")
+	}
+
+	if err = registerSecrets(); err != nil {
+		return errors.Wrap(err, "failed to register secrets")
+	}
+
+	return nil
+}
+
+// registerSecrets masks secrets from config in all logs.
+func registerSecrets() error {
+	redact.AddKeys(config.SensitiveKeys...)
+	redact.AddSecret(config.HetznerToken, config.Backup.SecretKey)
+
+	configValues, err := values()
+	if err != nil {
+		return err
+	}
+
+	redact.AddSecretsFrom(configValues)
+
 	return nil
 }
 
+// values returns config as generic map.
+func values() (map[string]interface{}, error) {
+	configYAML, err := yaml.Marshal(&config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling config")
+	}
+
+	result := make(map[string]interface{})
+
+	if err := yaml.Unmarshal(configYAML, &result); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling config")
+	}
+
+	return result, nil
+}
+
+// AutoscalingGroups returns node groups from cluster-autoscaler values.
+func (t *Type) AutoscalingGroups() ([]ClusterAutoscalingGroup, error) {
+	values, err := yaml.Marshal(t.ClusterAutoscaler)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling cluster-autoscaler values")
+	}
+
+	result := struct {
+		AutoscalingGroups []ClusterAutoscalingGroup `yaml:"autoscalingGroups"`
+	}{}
+
+	if err := yaml.Unmarshal(values, &result); err != nil {
+		return nil, errors.Wrap(err, "error parsing cluster-autoscaler values")
+	}
+
+	return result.AutoscalingGroups, nil
+}
+
+// Offline returns true if action is executed without Hetzner Cloud API,
+// cost is estimated from pricing snapshot when token is not set.
+func Offline() bool {
+	return strings.EqualFold(*config.CliArgs.Action, "estimate-cost") && len(config.HetznerToken) == 0
+}
+
 func Check() error {
-	if len(config.HetznerToken) == 0 {
+	if len(config.HetznerToken) == 0 && !Offline() {
 		return errNoHetznerToken
 	}
 
+	if err := checkLoadBalancer(); err != nil {
+		return errors.Wrap(err, "invalid masterLoadBalancer")
+	}
+
+	if err := checkBackup(); err != nil {
+		return errors.Wrap(err, "invalid backup")
+	}
+
 	return nil
 }
 
-func Get() *Type {
-	return &config
+func checkLoadBalancer() error {
+	switch config.MasterLoadBalancer.Algorithm {
+	case hcloud.LoadBalancerAlgorithmTypeRoundRobin, hcloud.LoadBalancerAlgorithmTypeLeastConnections:
+	default:
+		return errors.Wrap(errUnknownAlgorithm, string(config.MasterLoadBalancer.Algorithm))
+	}
+
+	switch config.MasterLoadBalancer.HealthCheck.Protocol {
+	case hcloud.LoadBalancerServiceProtocolTCP, hcloud.LoadBalancerServiceProtocolHTTP:
+	default:
+		return errors.Wrapf(errUnknownProtocol, "health check: %s", config.MasterLoadBalancer.HealthCheck.Protocol)
+	}
+
+	listenPorts := map[int]bool{config.MasterLoadBalancer.ListenPort: true}
+
+	for _, service := range config.MasterLoadBalancer.Services {
+		switch service.Protocol {
+		case hcloud.LoadBalancerServiceProtocolTCP, hcloud.LoadBalancerServiceProtocolHTTP:
+		default:
+			return errors.Wrapf(errUnknownProtocol, "service %d: %s", service.ListenPort, service.Protocol)
+		}
+
+		if service.ListenPort <= 0 || service.DestinationPort <= 0 {
+			return errors.Wrapf(errInvalidPort, "service %d", service.ListenPort)
+		}
+
+		if listenPorts[service.ListenPort] {
+			return errors.Wrapf(errDuplicatePort, "service %d", service.ListenPort)
+		}
+
+		listenPorts[service.ListenPort] = true
+
+		switch service.HealthCheck.Protocol {
+		case "", hcloud.LoadBalancerServiceProtocolTCP, hcloud.LoadBalancerServiceProtocolHTTP:
+		default:
+			return errors.Wrapf(errUnknownProtocol, "service %d health check: %s", service.ListenPort, service.HealthCheck.Protocol) //nolint:lll
+		}
+	}
+
+	return nil
 }
 
-func hideSensitiveData(out []byte, sensitive string) []byte {
-	if len(sensitive) == 0 {
-		return out
+// ubuntu versions with curl older than 7.75.
+//
+//nolint:gochecknoglobals
+var backupUnsupportedUbuntu = []string{"ubuntu-18.04", "ubuntu-20.04"}
+
+func checkBackup() error {
+	if !config.Backup.Enabled() {
+		return nil
+	}
+
+	if len(config.Backup.Endpoint) == 0 {
+		return errors.Wrap(errBackupNotSet, "endpoint")
+	}
+
+	if len(config.Backup.AccessKey) == 0 || len(config.Backup.SecretKey) == 0 {
+		return errors.Wrap(errBackupNotSet, "credentials")
+	}
+
+	if config.Backup.Retention <= 0 {
+		return errors.Wrap(errBackupNotSet, "retention")
+	}
+
+	if _, err := time.ParseDuration(config.Backup.Schedule); err != nil {
+		return errors.Wrap(err, "schedule")
+	}
+
+	// scheduled backup signs requests with curl --aws-sigv4, it needs curl 7.75+
+	if slices.Contains(backupUnsupportedUbuntu, config.ServerComponents.Ubuntu.Version) {
+		return errors.Wrap(errBackupUnsupported, config.ServerComponents.Ubuntu.Version)
 	}
 
-	return bytes.ReplaceAll(out, []byte(sensitive), []byte(secretString))
+	return nil
+}
+
+func Get() *Type {
+	return &config
 }
 
 func String() string {
@@ -350,11 +648,7 @@ func String() string {
 		return fmt.Sprintf("ERROR %s", err)
 	}
 
-	out := b.Bytes()
-	// remove sensitive data
-	out = hideSensitiveData(out, config.HetznerToken)
-
-	return string(out)
+	return redact.String(b.String())
 }
 
 func expand(path string) (string, error) {
@@ -381,7 +675,7 @@ func envDefault(name string, defaultValue string) string {
 func SaveConfig(filePath string) error {
 	const configPermissions = 0o600
 
-	re := regexp.MustCompile("(?m)[\r\n]+^.*(kubeConfigPath|hetznerToken|sshPrivateKey|sshPublicKey).*$")
+	re := regexp.MustCompile("(?m)[\r\n]+^.*(kubeConfigPath|hetznerToken|sshPrivateKey|sshPublicKey|sshKnownHosts).*$")
 
 	content := re.ReplaceAllString(String(), "")
 
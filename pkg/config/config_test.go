@@ -13,10 +13,14 @@ limitations under the License.
 package config_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/config"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
 )
 
 func TestConfig(t *testing.T) {
@@ -45,4 +49,99 @@ func TestConfig(t *testing.T) {
 	if strings.Contains(config.String(), "sometoken") {
 		t.Fatal("config has secret tokens")
 	}
+
+	if !strings.Contains(fmt.Sprint(config.Get().Deployments), "somepassword") {
+		t.Fatal("secret reference is not resolved")
+	}
+
+	if !strings.Contains(fmt.Sprint(config.Get().Deployments), "file:///etc/ssl/certs/ca-certificates.crt") {
+		t.Fatal("value without secretRef is resolved")
+	}
+
+	if strings.Contains(redact.String("helm --set password=somepassword"), "somepassword") {
+		t.Fatal("user-marked secret is not masked")
+	}
+
+	hooks, err := config.RenderHooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "echo k8s ${NODE_ROLE} ${NODE_NAME} ${INTERNAL_IP} 33"; hooks.Masters.PreStartScript != want {
+		t.Fatalf("want=%s, got=%s", want, hooks.Masters.PreStartScript)
+	}
+
+	groups, err := config.Get().AutoscalingGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) == 0 || groups[0].MaxSize == 0 || len(groups[0].InstanceType) == 0 {
+		t.Fatalf("unexpected autoscaling groups %+v", groups)
+	}
+}
+
+func TestHooksRender(t *testing.T) {
+	t.Parallel()
+
+	invalidHooks := []config.Hooks{
+		{Masters: config.NodeHooks{PreStartScript: "{{ .NodeName"}},
+		{Workers: config.NodeHooks{PostStartScript: "{{ .Unknown }}"}},
+		{FirstMaster: config.NodeHooks{PreStartScript: "{{ .Values.unknown }}"}},
+	}
+
+	for _, hooks := range invalidHooks {
+		if _, err := hooks.Render(map[string]interface{}{}); err == nil {
+			t.Fatalf("error expected for %+v", hooks)
+		}
+	}
+
+	hooks, err := config.Hooks{
+		JoiningMasters: config.NodeHooks{PostStartScript: "echo {{ .Values.clusterName }}"},
+	}.Render(map[string]interface{}{"clusterName": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hooks.JoiningMasters.PostStartScript != "echo test" {
+		t.Fatalf("unexpected hook %s", hooks.JoiningMasters.PostStartScript)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("HCLOUD_K8S_CTL_TEST_SECRET", "envsecret")
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+
+	if err := os.WriteFile(secretFile, []byte("filesecret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"plain":                            "plain",
+		"env://HCLOUD_K8S_CTL_TEST_SECRET": "envsecret",
+		"file://" + secretFile:             "filesecret",
+		"exec://echo execsecret":           "execsecret",
+	}
+
+	for value, want := range tests {
+		got, err := config.ResolveSecret(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Fatalf("want=%s, got=%s", want, got)
+		}
+	}
+
+	if redact.String("token execsecret") != "token "+redact.Mask {
+		t.Fatal("resolved secret is not masked")
+	}
+
+	for _, value := range []string{"env://HCLOUD_K8S_CTL_TEST_UNKNOWN", "file:///unknown", "exec://exit 1"} {
+		if _, err := config.ResolveSecret(value); err == nil {
+			t.Fatalf("error expected for %s", value)
+		}
+	}
 }
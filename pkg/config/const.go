@@ -21,14 +21,24 @@ import (
 var hcloudInstanceTypes string
 
 const (
-	masterServersCount          = 3
-	loadBalancerDefaultPort     = 6443
-	waitTimeInRetry             = 3 * time.Second
-	retryTimeLimit              = 20
-	secretString                = "<secret>"
-	defaultLocation             = hcloudLocationEUHelsinki
-	defaultDatacenter           = hcloudLocationEUHelsinki + "-dc2"
-	hcloudLocationEUFalkenstein = "fsn1"
-	hcloudLocationEUNuremberg   = "nbg1"
-	hcloudLocationEUHelsinki    = "hel1"
+	masterServersCount              = 3
+	loadBalancerDefaultPort         = 6443
+	loadBalancerHealthCheckInterval = 15 * time.Second
+	loadBalancerHealthCheckTimeout  = 10 * time.Second
+	loadBalancerHealthCheckRetries  = 3
+	waitTimeInRetry                 = 3 * time.Second
+	retryTimeLimit                  = 20
+	backupRetention                 = 10
+	backupSchedule                  = "12h"
+	backupEtcdKeep                  = 5
+	issueKubeconfigTTL              = 24 * time.Hour
+	collectDiagnosticsSince         = 24 * time.Hour
+	drainTimeout                    = 5 * time.Minute
+	createVerifyTimeout             = 10 * time.Minute
+	gcMinAge                        = 30 * time.Minute
+	defaultLocation                 = hcloudLocationEUHelsinki
+	defaultDatacenter               = hcloudLocationEUHelsinki + "-dc2"
+	hcloudLocationEUFalkenstein     = "fsn1"
+	hcloudLocationEUNuremberg       = "nbg1"
+	hcloudLocationEUHelsinki        = "hel1"
 )
@@ -14,4 +14,14 @@ package config
 
 import "errors"
 
-var errNoHetznerToken = errors.New("hetzner token is not set")
+var (
+	errNoHetznerToken    = errors.New("hetzner token is not set")
+	errUnknownAlgorithm  = errors.New("unknown load balancer algorithm")
+	errUnknownProtocol   = errors.New("unknown load balancer protocol")
+	errInvalidPort       = errors.New("invalid load balancer port")
+	errDuplicatePort     = errors.New("duplicate load balancer listen port")
+	errBackupNotSet      = errors.New("backup option is not set")
+	errSecretNotFound    = errors.New("secret not found")
+	errBackupUnsupported = errors.New("scheduled backup needs curl 7.75+, use ubuntu 22.04 or newer")
+	errInvalidSecretRef  = errors.New("secretRef must be env://, file:// or exec:// reference")
+)
@@ -0,0 +1,136 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// node roles, passed to common-install.sh in NODE_ROLE environment variable.
+const (
+	NodeRoleFirstMaster   = "first-master"
+	NodeRoleJoiningMaster = "joining-master"
+	NodeRoleWorker        = "worker"
+	NodeRoleImageBuilder  = "image-builder"
+)
+
+// node specific values are known only on node, templates are rendered
+// to shell variables that are defined in common-install.sh.
+const (
+	hookNodeName  = "${NODE_NAME}"
+	hookNodeRole  = "${NODE_ROLE}"
+	hookPrivateIP = "${INTERNAL_IP}"
+)
+
+type NodeHooks struct {
+	PreStartScript  string `yaml:"preStartScript"`
+	PostStartScript string `yaml:"postStartScript"`
+}
+
+// Hooks are scripts for nodes with specific role, executed after
+// global preStartScript and postStartScript.
+type Hooks struct {
+	Masters        NodeHooks `yaml:"masters"`
+	FirstMaster    NodeHooks `yaml:"firstMaster"`
+	JoiningMasters NodeHooks `yaml:"joiningMasters"`
+	Workers        NodeHooks `yaml:"workers"`
+}
+
+// HookData is data for hooks templates.
+type HookData struct {
+	ClusterName string
+	NodeName    string
+	Role        string
+	PrivateIP   string
+	Values      map[string]interface{}
+}
+
+// Render executes all hooks templates, values is a cluster config.
+func (h Hooks) Render(values map[string]interface{}) (Hooks, error) {
+	data := HookData{
+		NodeName:  hookNodeName,
+		Role:      hookNodeRole,
+		PrivateIP: hookPrivateIP,
+		Values:    values,
+	}
+
+	if clusterName, ok := values["clusterName"].(string); ok {
+		data.ClusterName = clusterName
+	}
+
+	var err error
+
+	if h.Masters, err = h.Masters.render("masters", data); err != nil {
+		return Hooks{}, err
+	}
+
+	if h.FirstMaster, err = h.FirstMaster.render("firstMaster", data); err != nil {
+		return Hooks{}, err
+	}
+
+	if h.JoiningMasters, err = h.JoiningMasters.render("joiningMasters", data); err != nil {
+		return Hooks{}, err
+	}
+
+	if h.Workers, err = h.Workers.render("workers", data); err != nil {
+		return Hooks{}, err
+	}
+
+	return h, nil
+}
+
+func (h NodeHooks) render(name string, data HookData) (NodeHooks, error) {
+	var err error
+
+	if h.PreStartScript, err = renderHook(name+".preStartScript", h.PreStartScript, data); err != nil {
+		return NodeHooks{}, err
+	}
+
+	if h.PostStartScript, err = renderHook(name+".postStartScript", h.PostStartScript, data); err != nil {
+		return NodeHooks{}, err
+	}
+
+	return h, nil
+}
+
+func renderHook(name string, text string, data HookData) (string, error) {
+	if len(text) == 0 {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing hook %s", name)
+	}
+
+	var b bytes.Buffer
+
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "error executing hook %s", name)
+	}
+
+	return b.String(), nil
+}
+
+// RenderHooks returns hooks from current config with executed templates.
+func RenderHooks() (Hooks, error) {
+	configValues, err := values()
+	if err != nil {
+		return Hooks{}, err
+	}
+
+	return config.Hooks.Render(configValues)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	secretEnvPrefix  = "env://"
+	secretFilePrefix = "file://"
+	secretExecPrefix = "exec://"
+	// marks secret reference in chart values, other values are never resolved
+	secretRefPrefix = "secretRef:"
+)
+
+// ResolveSecret returns value of secret reference env://NAME, file://path or exec://command,
+// other values are returned as is. Resolved values are masked in logs.
+func ResolveSecret(value string) (string, error) {
+	var (
+		result string
+		err    error
+	)
+
+	switch {
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+
+		var ok bool
+		if result, ok = os.LookupEnv(name); !ok {
+			return "", errors.Wrap(errSecretNotFound, value)
+		}
+	case strings.HasPrefix(value, secretFilePrefix):
+		result, err = readSecretFile(strings.TrimPrefix(value, secretFilePrefix))
+	case strings.HasPrefix(value, secretExecPrefix):
+		result, err = execSecret(strings.TrimPrefix(value, secretExecPrefix))
+	default:
+		return value, nil
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, value)
+	}
+
+	result = strings.TrimSpace(result)
+
+	redact.AddSecret(result)
+
+	return result, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	path, err := expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading secret file")
+	}
+
+	return string(content), nil
+}
+
+func execSecret(command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("sh", "-c", command) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "error executing secret command: %s", stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+// resolveSecrets resolves secret references in token, backup keys and marked chart values.
+func (t *Type) resolveSecrets() error {
+	var err error
+
+	for _, value := range []*string{&t.HetznerToken, &t.Backup.AccessKey, &t.Backup.SecretKey} {
+		if *value, err = ResolveSecret(*value); err != nil {
+			return err
+		}
+	}
+
+	if t.Deployments, err = resolveSecrets(t.Deployments); err != nil {
+		return err
+	}
+
+	for _, values := range []map[interface{}]interface{}{
+		t.ClusterAutoscaler,
+		t.NfsSubdirExternalProvisioner,
+		t.KubeletCSRApprover,
+		t.HCloudCSI,
+		t.HCloudCCM,
+		t.MetricsServer,
+		t.Flannel,
+	} {
+		if _, err = resolveSecrets(values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveSecrets resolves secretRef: marked string values of maps and slices.
+func resolveSecrets(values interface{}) (interface{}, error) {
+	switch typed := values.(type) {
+	case string:
+		return resolveSecretRef(typed)
+	case map[string]interface{}:
+		for key, value := range typed {
+			resolved, err := resolveSecrets(value)
+			if err != nil {
+				return nil, err
+			}
+
+			typed[key] = resolved
+		}
+	case map[interface{}]interface{}:
+		for key, value := range typed {
+			resolved, err := resolveSecrets(value)
+			if err != nil {
+				return nil, err
+			}
+
+			typed[key] = resolved
+		}
+	case []interface{}:
+		for i, value := range typed {
+			resolved, err := resolveSecrets(value)
+			if err != nil {
+				return nil, err
+			}
+
+			typed[i] = resolved
+		}
+	}
+
+	return values, nil
+}
+
+// resolveSecretRef resolves value like secretRef:env://NAME, other values are returned as is.
+func resolveSecretRef(value string) (string, error) {
+	reference, ok := strings.CutPrefix(value, secretRefPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	if !strings.HasPrefix(reference, secretEnvPrefix) &&
+		!strings.HasPrefix(reference, secretFilePrefix) &&
+		!strings.HasPrefix(reference, secretExecPrefix) {
+		return "", errors.Wrap(errInvalidSecretRef, value)
+	}
+
+	return ResolveSecret(reference)
+}
+
+// loadSecretsOverlay merges sops encrypted config overlay into config.
+func loadSecretsOverlay(configByte []byte, path string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("sops", "--decrypt", path) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "error decrypting %s: %s", path, stderr.String())
+	}
+
+	base := make(map[string]interface{})
+
+	if err := yaml.Unmarshal(configByte, &base); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config file")
+	}
+
+	overlay := make(map[string]interface{})
+
+	if err := yaml.Unmarshal(stdout.Bytes(), &overlay); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal secrets file")
+	}
+
+	// sops metadata
+	delete(overlay, "sops")
+
+	// all overlay values are secrets
+	addOverlaySecrets(overlay)
+
+	result, err := yaml.Marshal(mergeValues(base, overlay))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal config")
+	}
+
+	return result, nil
+}
+
+func addOverlaySecrets(values interface{}) {
+	switch typed := values.(type) {
+	case string:
+		redact.AddSecret(typed)
+	case map[string]interface{}:
+		for _, value := range typed {
+			addOverlaySecrets(value)
+		}
+	case []interface{}:
+		for _, value := range typed {
+			addOverlaySecrets(value)
+		}
+	}
+}
+
+// mergeValues deep merges overlay maps into base, other overlay values replace base values.
+func mergeValues(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		overlayMap, ok := value.(map[string]interface{})
+		if !ok {
+			base[key] = value
+
+			continue
+		}
+
+		baseMap, ok := base[key].(map[string]interface{})
+		if !ok {
+			base[key] = overlayMap
+
+			continue
+		}
+
+		base[key] = mergeValues(baseMap, overlayMap)
+	}
+
+	return base
+}
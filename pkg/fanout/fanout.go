@@ -0,0 +1,194 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// Result is result of task on one host.
+type Result struct {
+	Name     string
+	Status   string
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	Err      error
+}
+
+// Task is executed on one host, stdout and stderr are returned also on error.
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) (string, string, error)
+}
+
+type Options struct {
+	// maximum number of tasks running at same time, 0 is unlimited
+	Parallel int
+	// timeout of each task, 0 is unlimited
+	Timeout time.Duration
+	// cancel other tasks after first failure
+	FailFast bool
+}
+
+// Aggregator collects results from concurrent tasks.
+type Aggregator struct {
+	mutex   sync.Mutex
+	results map[string]Result
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{results: make(map[string]Result)}
+}
+
+// Add adds result, status is set from error if empty.
+func (a *Aggregator) Add(result Result) {
+	if len(result.Status) == 0 {
+		result.Status = status(result.Err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.results[result.Name] = result
+}
+
+// Results returns results sorted by name.
+func (a *Aggregator) Results() []Result {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	results := make([]Result, 0, len(a.results))
+
+	for _, result := range a.results {
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
+
+// Failed returns number of results that are not ok.
+func (a *Aggregator) Failed() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	failed := 0
+
+	for _, result := range a.results {
+		if result.Status != StatusOK {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Run executes tasks concurrently and returns results sorted by name,
+// panics in tasks are returned as errors.
+func Run(ctx context.Context, tasks []Task, opts Options) []Result {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parallel := opts.Parallel
+	if parallel <= 0 || parallel > len(tasks) {
+		parallel = len(tasks)
+	}
+
+	var wg sync.WaitGroup
+
+	aggregator := NewAggregator()
+	semaphore := make(chan struct{}, parallel)
+
+	for _, task := range tasks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result := runTask(ctx, task, opts.Timeout)
+
+			aggregator.Add(result)
+
+			if opts.FailFast && result.Status == StatusFailed {
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return aggregator.Results()
+}
+
+func runTask(ctx context.Context, task Task, timeout time.Duration) (result Result) {
+	result.Name = task.Name
+
+	if err := ctx.Err(); err != nil {
+		result.Err = errors.Wrap(err, "task not started")
+		result.Status = StatusCanceled
+
+		return result
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("task panic: %v", r) //nolint:err113
+		}
+
+		result.Duration = time.Since(start)
+		result.Status = status(result.Err)
+	}()
+
+	result.Stdout, result.Stderr, result.Err = task.Run(ctx)
+
+	return result
+}
+
+func status(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.Is(err, context.Canceled):
+		return StatusCanceled
+	default:
+		return StatusFailed
+	}
+}
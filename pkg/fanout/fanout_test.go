@@ -0,0 +1,200 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fanout_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/fanout"
+)
+
+var errTask = errors.New("task error")
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+
+	aggregator := fanout.NewAggregator()
+
+	var wg sync.WaitGroup
+
+	for i := range 100 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if i%10 == 0 {
+				err = errTask
+			}
+
+			aggregator.Add(fanout.Result{Name: fmt.Sprintf("server-%03d", i), Err: err})
+		}()
+	}
+
+	wg.Wait()
+
+	results := aggregator.Results()
+
+	if len(results) != 100 || results[0].Name != "server-000" || results[99].Name != "server-099" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	if results[0].Status != fanout.StatusFailed || results[1].Status != fanout.StatusOK {
+		t.Fatalf("unexpected status %+v", results[:2])
+	}
+
+	if failed := aggregator.Failed(); failed != 10 {
+		t.Fatalf("want=10, got=%d", failed)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int32
+
+	tasks := make([]fanout.Task, 0)
+
+	for i := range 20 {
+		tasks = append(tasks, fanout.Task{
+			Name: fmt.Sprintf("server-%02d", i),
+			Run: func(_ context.Context) (string, string, error) {
+				current := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					previous := maxRunning.Load()
+					if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				if i == 5 {
+					return "out", "err", errTask
+				}
+
+				if i == 6 {
+					panic("boom")
+				}
+
+				return "out", "", nil
+			},
+		})
+	}
+
+	results := fanout.Run(t.Context(), tasks, fanout.Options{Parallel: 3})
+
+	if maxRunning.Load() > 3 {
+		t.Fatalf("parallel limit is not respected, running=%d", maxRunning.Load())
+	}
+
+	if len(results) != 20 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	for i, result := range results {
+		want := fanout.StatusOK
+		if i == 5 || i == 6 {
+			want = fanout.StatusFailed
+		}
+
+		if result.Status != want {
+			t.Fatalf("%s: want=%s, got=%s", result.Name, want, result.Status)
+		}
+
+		if result.Stdout != "out" && i != 6 {
+			t.Fatalf("%s: stdout is not collected", result.Name)
+		}
+	}
+
+	if results[5].Stderr != "err" || !errors.Is(results[5].Err, errTask) {
+		t.Fatalf("unexpected result %+v", results[5])
+	}
+
+	if results[6].Err == nil || results[6].Duration == 0 {
+		t.Fatalf("panic is not collected %+v", results[6])
+	}
+}
+
+func TestRunFailFast(t *testing.T) {
+	t.Parallel()
+
+	tasks := []fanout.Task{
+		{
+			Name: "a-failed",
+			Run: func(_ context.Context) (string, string, error) {
+				return "", "", errTask
+			},
+		},
+		{
+			Name: "b-running",
+			Run: func(ctx context.Context) (string, string, error) {
+				<-ctx.Done()
+
+				return "", "", ctx.Err()
+			},
+		},
+		{
+			Name: "c-not-started",
+			Run: func(_ context.Context) (string, string, error) {
+				return "", "", nil
+			},
+		},
+	}
+
+	// second task waits for cancel, third task waits for free slot
+	results := fanout.Run(t.Context(), tasks, fanout.Options{Parallel: 2, FailFast: true})
+
+	if results[0].Status != fanout.StatusFailed {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+
+	if results[1].Status != fanout.StatusCanceled {
+		t.Fatalf("unexpected result %+v", results[1])
+	}
+
+	// third task is started after first or second task, first task cancels all other tasks
+	if results[2].Status == fanout.StatusFailed {
+		t.Fatalf("unexpected result %+v", results[2])
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	tasks := []fanout.Task{
+		{
+			Name: "slow",
+			Run: func(ctx context.Context) (string, string, error) {
+				<-ctx.Done()
+
+				return "", "", ctx.Err()
+			},
+		},
+	}
+
+	results := fanout.Run(t.Context(), tasks, fanout.Options{Timeout: 10 * time.Millisecond})
+
+	if results[0].Status != fanout.StatusFailed || !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+}
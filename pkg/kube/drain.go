@@ -0,0 +1,123 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kube
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// Drain cordons node and evicts all pods except daemonset and static pods,
+// eviction blocked by PodDisruptionBudget is retried until timeout.
+func (c *Client) Drain(ctx context.Context, name string, timeout time.Duration) error {
+	if err := c.SetUnschedulable(ctx, name, true); err != nil {
+		return err
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, c.Interval, timeout, true, func(ctx context.Context) (bool, error) {
+		pods, err := c.podsToEvict(ctx, name)
+		if err != nil {
+			log.WithError(err).Debug("error listing pods")
+
+			return false, nil
+		}
+
+		if len(pods) == 0 {
+			return true, nil
+		}
+
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			if err := c.evict(ctx, pod); err != nil {
+				log.WithError(err).Warnf("error evicting pod %s/%s", pod.Namespace, pod.Name)
+			}
+		}
+
+		log.Infof("Waiting for %d pods evicted from %s...", len(pods), name)
+
+		return false, nil
+	})
+
+	return errors.Wrapf(err, "error draining node %s", name)
+}
+
+func (c *Client) evict(ctx context.Context, pod corev1.Pod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return errors.Wrap(err, "error creating eviction")
+}
+
+// podsToEvict returns pods on node that are not managed by daemonset and are not static pods.
+func (c *Client) podsToEvict(ctx context.Context, name string) ([]corev1.Pod, error) {
+	pods, err := c.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing pods")
+	}
+
+	result := make([]corev1.Pod, 0)
+
+	for _, pod := range pods.Items {
+		// field selector is not supported by all clients
+		if pod.Spec.NodeName != name {
+			continue
+		}
+
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+
+		if isDaemonSetPod(pod) {
+			continue
+		}
+
+		result = append(result, pod)
+	}
+
+	return result, nil
+}
+
+func isDaemonSetPod(pod corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
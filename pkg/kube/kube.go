@@ -0,0 +1,186 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultInterval = 5 * time.Second
+
+	LabelControlPlane = "node-role.kubernetes.io/control-plane"
+	LabelMaster       = "node-role.kubernetes.io/master" // masters of clusters created before kubernetes 1.20
+	LabelHostname     = "kubernetes.io/hostname"
+
+	// providerID of nodes set by hcloud-cloud-controller-manager
+	hcloudProviderPrefix = "hcloud://"
+)
+
+// Client is kubernetes operations used by cli.
+type Client struct {
+	clientset kubernetes.Interface
+	// polling interval of waits
+	Interval time.Duration
+}
+
+func New(clientset kubernetes.Interface) *Client {
+	return &Client{
+		clientset: clientset,
+		Interval:  defaultInterval,
+	}
+}
+
+// NewFromKubeconfig creates client from kubeconfig, current context is used if contextName is empty.
+func NewFromKubeconfig(kubeconfig []byte, contextName string) (*Client, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading kubeconfig")
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig() //nolint:lll
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating rest config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating clientset")
+	}
+
+	return New(clientset), nil
+}
+
+// IsNodeReady returns true if node has Ready condition.
+func IsNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// ReadyNodes returns number of Ready nodes matching label selector.
+func (c *Client) ReadyNodes(ctx context.Context, selector string) (int, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, errors.Wrap(err, "error listing nodes")
+	}
+
+	ready := 0
+
+	for i := range nodes.Items {
+		if IsNodeReady(&nodes.Items[i]) {
+			ready++
+		}
+	}
+
+	return ready, nil
+}
+
+// WaitNodesReady waits until at least count nodes matching label selector are Ready.
+func (c *Client) WaitNodesReady(ctx context.Context, selector string, count int, timeout time.Duration) error {
+	err := wait.PollUntilContextTimeout(ctx, c.Interval, timeout, true, func(ctx context.Context) (bool, error) {
+		ready, err := c.ReadyNodes(ctx, selector)
+		if err != nil {
+			log.WithError(err).Debug("error listing nodes")
+
+			return false, nil
+		}
+
+		log.Debugf("nodes %s ready %d/%d", selector, ready, count)
+
+		return ready >= count, nil
+	})
+
+	return errors.Wrapf(err, "error waiting %d nodes %s ready", count, selector)
+}
+
+// SetUnschedulable cordons or uncordons node.
+func (c *Client) SetUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": unschedulable,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating patch")
+	}
+
+	_, err = c.clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+
+	return errors.Wrapf(err, "error patching node %s", name)
+}
+
+// PatchNodes sets labels and annotations on nodes matching label selector, returns number of patched nodes.
+func (c *Client) PatchNodes(ctx context.Context, selector string, labels, annotations map[string]string) (int, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating patch")
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, errors.Wrap(err, "error listing nodes")
+	}
+
+	for _, node := range nodes.Items {
+		if _, err := c.clientset.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil { //nolint:lll
+			return 0, errors.Wrapf(err, "error patching node %s", node.Name)
+		}
+	}
+
+	return len(nodes.Items), nil
+}
+
+// DeleteNode deletes node object from cluster.
+func (c *Client) DeleteNode(ctx context.Context, name string) error {
+	err := c.clientset.CoreV1().Nodes().Delete(ctx, name, metav1.DeleteOptions{})
+
+	return errors.Wrapf(err, "error deleting node %s", name)
+}
+
+// ServerID returns hetzner cloud server id from node providerID, false if providerID is not hcloud server.
+func ServerID(providerID string) (int64, bool) {
+	id, ok := strings.CutPrefix(providerID, hcloudProviderPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	serverID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return serverID, true
+}
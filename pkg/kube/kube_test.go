@@ -0,0 +1,298 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kube_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newNode(name string, ready bool, labels map[string]string) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.33.7"},
+		},
+	}
+}
+
+func newPod(name, node string, ownerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: []corev1.Container{{Name: "app"}},
+		},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true}},
+		},
+	}
+
+	if len(ownerKind) > 0 {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner"}}
+	}
+
+	return pod
+}
+
+func newClient(objects ...runtime.Object) (*kube.Client, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+
+	client := kube.New(clientset)
+	client.Interval = 10 * time.Millisecond
+
+	return client, clientset
+}
+
+func TestWaitNodesReady(t *testing.T) {
+	t.Parallel()
+
+	master := map[string]string{kube.LabelControlPlane: ""}
+
+	client, clientset := newClient(
+		newNode("master-1", true, master),
+		newNode("master-2", false, master),
+		newNode("worker-1", false, nil),
+	)
+
+	if err := client.WaitNodesReady(t.Context(), kube.LabelControlPlane, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WaitNodesReady(t.Context(), kube.LabelControlPlane, 2, 50*time.Millisecond); err == nil {
+		t.Fatal("error expected")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+
+		_, _ = clientset.CoreV1().Nodes().Update(context.Background(), newNode("master-2", true, master), metav1.UpdateOptions{}) //nolint:lll
+	}()
+
+	if err := client.WaitNodesReady(t.Context(), kube.LabelControlPlane, 2, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPatchNodes(t *testing.T) {
+	t.Parallel()
+
+	client, clientset := newClient(
+		newNode("master-1", true, map[string]string{kube.LabelControlPlane: ""}),
+		newNode("master-2", true, map[string]string{kube.LabelControlPlane: "", kube.LabelMaster: ""}),
+		newNode("master-3", true, map[string]string{kube.LabelMaster: ""}),
+		newNode("worker-1", true, nil),
+	)
+
+	legacy, err := client.PatchNodes(t.Context(), kube.LabelMaster+",!"+kube.LabelControlPlane, nil, map[string]string{"c": "d"}) //nolint:lll
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if legacy != 1 {
+		t.Fatalf("want=1, got=%d", legacy)
+	}
+
+	patched, err := client.PatchNodes(t.Context(), kube.LabelControlPlane, map[string]string{"a": "b"}, map[string]string{"c": "d"}) //nolint:lll
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patched != 2 {
+		t.Fatalf("want=2, got=%d", patched)
+	}
+
+	master, err := clientset.CoreV1().Nodes().Get(t.Context(), "master-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if master.Labels["a"] != "b" || master.Annotations["c"] != "d" {
+		t.Fatalf("node is not patched %+v", master.ObjectMeta)
+	}
+
+	if _, ok := master.Labels[kube.LabelControlPlane]; !ok {
+		t.Fatal("existing labels must be kept")
+	}
+
+	worker, err := clientset.CoreV1().Nodes().Get(t.Context(), "worker-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(worker.Annotations) > 0 {
+		t.Fatal("worker must not be patched")
+	}
+}
+
+func TestServerID(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int64{
+		"hcloud://123456": 123456,
+		"hcloud://bm-123": 0,
+		"aws:///i-123":    0,
+		"":                0,
+	}
+
+	for providerID, want := range tests {
+		id, ok := kube.ServerID(providerID)
+		if id != want || ok != (want > 0) {
+			t.Fatalf("providerID=%s id=%d ok=%t, want %d", providerID, id, ok, want)
+		}
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+	t.Parallel()
+
+	client, clientset := newClient(newNode("worker-1", false, nil))
+
+	if err := client.DeleteNode(t.Context(), "worker-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(t.Context(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes.Items) != 0 {
+		t.Fatalf("nodes=%d, want 0", len(nodes.Items))
+	}
+
+	if err := client.DeleteNode(t.Context(), "worker-1"); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	mirrorPod := newPod("static", "worker-1", "")
+	mirrorPod.Annotations = map[string]string{"kubernetes.io/config.mirror": "hash"}
+
+	client, clientset := newClient(
+		newNode("worker-1", true, nil),
+		newPod("app", "worker-1", "ReplicaSet"),
+		newPod("agent", "worker-1", "DaemonSet"),
+		newPod("other", "worker-2", "ReplicaSet"),
+		mirrorPod,
+	)
+
+	evicted := make([]string, 0)
+
+	// fake clientset does not delete evicted pods
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction) //nolint:forcetypeassert
+
+		evicted = append(evicted, eviction.Name)
+
+		err := clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+
+		return true, nil, err
+	})
+
+	if err := client.Drain(t.Context(), "worker-1", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(evicted) != 1 || evicted[0] != "app" {
+		t.Fatalf("unexpected evicted pods %v", evicted)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(t.Context(), "worker-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !node.Spec.Unschedulable {
+		t.Fatal("node must be cordoned")
+	}
+
+	if err := client.SetUnschedulable(t.Context(), "worker-1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := client.Nodes(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !nodes[0].Schedulable {
+		t.Fatal("node must be uncordoned")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	failedPod := newPod("failed", "worker-1", "")
+	failedPod.Namespace = "kube-system"
+	failedPod.Status.Phase = corev1.PodPending
+	failedPod.Status.ContainerStatuses[0].Ready = false
+	failedPod.Status.ContainerStatuses[0].RestartCount = 3
+
+	runningPod := newPod("running", "worker-1", "")
+	runningPod.Namespace = "kube-system"
+
+	client, _ := newClient(
+		newNode("worker-1", false, nil),
+		newNode("master-1", true, map[string]string{kube.LabelControlPlane: ""}),
+		failedPod,
+		runningPod,
+	)
+
+	nodes, err := client.Nodes(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 || nodes[0].Name != "master-1" || nodes[0].Roles != "control-plane" || nodes[0].Status != kube.NodeReady {
+		t.Fatalf("unexpected nodes %+v", nodes)
+	}
+
+	if nodes[1].Status != kube.NodeNotReady || nodes[1].Roles != "<none>" || nodes[1].InternalIP != "10.0.0.2" {
+		t.Fatalf("unexpected node %+v", nodes[1])
+	}
+
+	pods, err := client.UnhealthyPods(t.Context(), "kube-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pods) != 1 || pods[0].Name != "failed" || pods[0].Ready != "0/1" || pods[0].Restarts != 3 {
+		t.Fatalf("unexpected pods %+v", pods)
+	}
+}
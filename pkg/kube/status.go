@@ -0,0 +1,156 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kube
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	nodeRolePrefix = "node-role.kubernetes.io/"
+	NodeReady      = "Ready"
+	NodeNotReady   = "NotReady"
+)
+
+type NodeStatus struct {
+	Name        string `json:"name"        yaml:"name"`
+	Roles       string `json:"roles"       yaml:"roles"`
+	Status      string `json:"status"      yaml:"status"`
+	Schedulable bool   `json:"schedulable" yaml:"schedulable"`
+	Version     string `json:"version"     yaml:"version"`
+	InternalIP  string `json:"internalIP"  yaml:"internalIP"`
+	ProviderID  string `json:"providerID"  yaml:"providerID"`
+	Age         string `json:"age"         yaml:"age"`
+}
+
+type PodStatus struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name"      yaml:"name"`
+	Node      string `json:"node"      yaml:"node"`
+	Phase     string `json:"phase"     yaml:"phase"`
+	Ready     string `json:"ready"     yaml:"ready"`
+	Restarts  int32  `json:"restarts"  yaml:"restarts"`
+}
+
+// Nodes returns status of all nodes sorted by name.
+func (c *Client) Nodes(ctx context.Context) ([]NodeStatus, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing nodes")
+	}
+
+	result := make([]NodeStatus, 0, len(nodes.Items))
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+
+		status := NodeStatus{
+			Name:        node.Name,
+			Roles:       nodeRoles(node),
+			Status:      NodeNotReady,
+			Schedulable: !node.Spec.Unschedulable,
+			Version:     node.Status.NodeInfo.KubeletVersion,
+			ProviderID:  node.Spec.ProviderID,
+			Age:         time.Since(node.CreationTimestamp.Time).Round(time.Minute).String(),
+		}
+
+		if IsNodeReady(node) {
+			status.Status = NodeReady
+		}
+
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP {
+				status.InternalIP = address.Address
+			}
+		}
+
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// UnhealthyPods returns pods in namespace that are not running with all containers ready and are not completed.
+func (c *Client) UnhealthyPods(ctx context.Context, namespace string) ([]PodStatus, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing pods")
+	}
+
+	result := make([]PodStatus, 0)
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+
+		ready, restarts := 0, int32(0)
+
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.Ready {
+				ready++
+			}
+
+			restarts += container.RestartCount
+		}
+
+		if pod.Status.Phase == corev1.PodRunning && ready == len(pod.Spec.Containers) {
+			continue
+		}
+
+		result = append(result, PodStatus{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Node:      pod.Spec.NodeName,
+			Phase:     string(pod.Status.Phase),
+			Ready:     strconv.Itoa(ready) + "/" + strconv.Itoa(len(pod.Spec.Containers)),
+			Restarts:  restarts,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace+"/"+result[i].Name < result[j].Namespace+"/"+result[j].Name
+	})
+
+	return result, nil
+}
+
+func nodeRoles(node *corev1.Node) string {
+	roles := make([]string, 0)
+
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRolePrefix); ok && len(role) > 0 {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return "<none>"
+	}
+
+	sort.Strings(roles)
+
+	return strings.Join(roles, ",")
+}
@@ -0,0 +1,132 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	fileMode      = 0o600
+	dirMode       = 0o700
+	backupDateFmt = "20060102150405"
+)
+
+// Rename returns kubeconfig with current context, its cluster and user renamed to name,
+// all other entries are removed.
+func Rename(kubeconfig []byte, name string) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing kubeconfig")
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, errors.Errorf("context %s not found", config.CurrentContext)
+	}
+
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return nil, errors.Errorf("cluster %s not found", context.Cluster)
+	}
+
+	user, ok := config.AuthInfos[context.AuthInfo]
+	if !ok {
+		return nil, errors.Errorf("user %s not found", context.AuthInfo)
+	}
+
+	result := clientcmdapi.NewConfig()
+	result.Clusters[name] = cluster
+	result.AuthInfos[name] = user
+	result.Contexts[name] = &clientcmdapi.Context{
+		Cluster:   name,
+		AuthInfo:  name,
+		Namespace: context.Namespace,
+	}
+	result.CurrentContext = name
+
+	return result, nil
+}
+
+// Merge adds all clusters, users and contexts from source to target,
+// target entries with same names are replaced, current context is switched to source.
+func Merge(target, source *clientcmdapi.Config) {
+	for name, cluster := range source.Clusters {
+		target.Clusters[name] = cluster
+	}
+
+	for name, user := range source.AuthInfos {
+		target.AuthInfos[name] = user
+	}
+
+	for name, context := range source.Contexts {
+		target.Contexts[name] = context
+	}
+
+	if len(source.CurrentContext) > 0 {
+		target.CurrentContext = source.CurrentContext
+	}
+}
+
+// Save writes kubeconfig to path, if merge is true kubeconfig is merged
+// with existing file, existing file is copied to backup. Returns backup path.
+func Save(path string, config *clientcmdapi.Config, merge bool) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return "", errors.Wrap(err, "error creating directory")
+	}
+
+	backupPath := ""
+
+	if merge {
+		existing, err := os.ReadFile(path)
+
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return "", errors.Wrap(err, "error reading kubeconfig")
+		default:
+			target, err := clientcmd.Load(existing)
+			if err != nil {
+				return "", errors.Wrapf(err, "error parsing %s", path)
+			}
+
+			backupPath = fmt.Sprintf("%s.%s.bak", path, time.Now().Format(backupDateFmt))
+
+			if err := os.WriteFile(backupPath, existing, fileMode); err != nil {
+				return "", errors.Wrap(err, "error creating backup")
+			}
+
+			Merge(target, config)
+
+			config = target
+		}
+	}
+
+	result, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", errors.Wrap(err, "error serializing kubeconfig")
+	}
+
+	if err := os.WriteFile(path, result, fileMode); err != nil {
+		return "", errors.Wrap(err, "error writing kubeconfig")
+	}
+
+	return backupPath, nil
+}
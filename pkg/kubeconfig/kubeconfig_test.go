@@ -0,0 +1,228 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/kubeconfig"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const adminConf = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: Y2E=
+    server: https://%s:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: kubernetes-admin
+  name: kubernetes-admin@kubernetes
+current-context: kubernetes-admin@kubernetes
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+`
+
+func TestRename(t *testing.T) {
+	t.Parallel()
+
+	config, err := kubeconfig.Rename([]byte(adminConf), "k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.CurrentContext != "k8s" {
+		t.Fatalf("unexpected current context %s", config.CurrentContext)
+	}
+
+	if len(config.Contexts) != 1 || config.Contexts["k8s"].Cluster != "k8s" || config.Contexts["k8s"].AuthInfo != "k8s" {
+		t.Fatalf("unexpected contexts %+v", config.Contexts)
+	}
+
+	if string(config.AuthInfos["k8s"].ClientKeyData) != "key" {
+		t.Fatal("user is not copied")
+	}
+
+	if _, err := kubeconfig.Rename([]byte("current-context: unknown"), "k8s"); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func TestSave(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".kube", "config")
+
+	first, err := kubeconfig.Rename([]byte(adminConf), "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, err := kubeconfig.Save(path, first, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backupPath) > 0 {
+		t.Fatal("backup must not be created for new file")
+	}
+
+	second, err := kubeconfig.Rename([]byte(adminConf), "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, err = kubeconfig.Save(path, second, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := clientcmd.LoadFromFile(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backup.Contexts) != 1 {
+		t.Fatalf("unexpected backup %+v", backup.Contexts)
+	}
+
+	merged, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(merged.Contexts) != 2 || merged.CurrentContext != "second" {
+		t.Fatalf("unexpected merged config %+v", merged)
+	}
+
+	if _, err := kubeconfig.Save(path, first, false); err != nil {
+		t.Fatal(err)
+	}
+
+	overwritten, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(overwritten.Contexts) != 1 || overwritten.CurrentContext != "first" {
+		t.Fatalf("unexpected overwritten config %+v", overwritten)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("unexpected mode %s", info.Mode())
+	}
+}
+
+func TestForUser(t *testing.T) {
+	t.Parallel()
+
+	csrPEM, keyPEM, err := kubeconfig.NewUserCSR("alice", []string{"devs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		t.Fatal("invalid csr")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if csr.Subject.CommonName != "alice" || len(csr.Subject.Organization) != 1 || csr.Subject.Organization[0] != "devs" {
+		t.Fatalf("unexpected subject %s", csr.Subject)
+	}
+
+	clusterConfig, err := kubeconfig.Rename([]byte(adminConf), "k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := kubeconfig.ForUser(clusterConfig, "alice", []byte("cert"), keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.CurrentContext != "alice@k8s" || config.Contexts["alice@k8s"].Cluster != "k8s" {
+		t.Fatalf("unexpected contexts %+v", config.Contexts)
+	}
+
+	if _, ok := config.AuthInfos["k8s"]; ok {
+		t.Fatal("admin user must not be copied")
+	}
+
+	if string(config.AuthInfos["alice@k8s"].ClientCertificateData) != "cert" {
+		t.Fatal("unexpected certificate")
+	}
+
+	if _, _, err := kubeconfig.NewUserCSR("", nil); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func TestCertificateNotAfter(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := kubeconfig.CertificateNotAfter(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Equal(notAfter) {
+		t.Fatalf("want=%s, got=%s", notAfter, result)
+	}
+
+	if _, err := kubeconfig.CertificateNotAfter([]byte("cert")); err == nil {
+		t.Fatal("error expected")
+	}
+}
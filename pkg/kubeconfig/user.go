@@ -0,0 +1,104 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// NewUserCSR generates private key and certificate request for user in groups,
+// both are PEM encoded.
+func NewUserCSR(user string, groups []string) ([]byte, []byte, error) {
+	if len(user) == 0 {
+		return nil, nil, errors.New("user is empty")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error generating key")
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   user,
+			Organization: groups,
+		},
+	}, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating certificate request")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error encoding key")
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return csrPEM, keyPEM, nil
+}
+
+// ForUser returns kubeconfig with clusters from cluster config and single user
+// authenticated with client certificate, context is named user@cluster.
+func ForUser(clusterConfig *clientcmdapi.Config, user string, certPEM, keyPEM []byte) (*clientcmdapi.Config, error) {
+	clusterContext, ok := clusterConfig.Contexts[clusterConfig.CurrentContext]
+	if !ok {
+		return nil, errors.Errorf("context %s not found", clusterConfig.CurrentContext)
+	}
+
+	cluster, ok := clusterConfig.Clusters[clusterContext.Cluster]
+	if !ok {
+		return nil, errors.Errorf("cluster %s not found", clusterContext.Cluster)
+	}
+
+	userName := user + "@" + clusterContext.Cluster
+
+	result := clientcmdapi.NewConfig()
+	result.Clusters[clusterContext.Cluster] = cluster
+	result.AuthInfos[userName] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: certPEM,
+		ClientKeyData:         keyPEM,
+	}
+	result.Contexts[userName] = &clientcmdapi.Context{
+		Cluster:  clusterContext.Cluster,
+		AuthInfo: userName,
+	}
+	result.CurrentContext = userName
+
+	return result, nil
+}
+
+// CertificateNotAfter returns expiration time of PEM encoded certificate.
+func CertificateNotAfter(certPEM []byte) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, errors.New("certificate is not PEM encoded")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error parsing certificate")
+	}
+
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatText  Format = ""
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatTable Format = "table"
+)
+
+var errUnknownFormat = errors.New("unknown output format")
+
+// Tabular is implemented by results that can be printed as table.
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatText, FormatJSON, FormatYAML, FormatTable:
+		return format, nil
+	default:
+		return "", errors.Wrap(errUnknownFormat, value)
+	}
+}
+
+// Write writes result in format, results that are not Tabular are written as yaml in table format.
+func Write(w io.Writer, format Format, result interface{}) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(result); err != nil {
+			return errors.Wrap(err, "error encoding json")
+		}
+	case FormatTable:
+		if tabular, ok := result.(Tabular); ok {
+			return writeTable(w, tabular)
+		}
+
+		return writeYAML(w, result)
+	default:
+		return writeYAML(w, result)
+	}
+
+	return nil
+}
+
+func writeYAML(w io.Writer, result interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2) //nolint:mnd
+
+	if err := encoder.Encode(result); err != nil {
+		return errors.Wrap(err, "error encoding yaml")
+	}
+
+	return errors.Wrap(encoder.Close(), "error encoding yaml")
+}
+
+func writeTable(w io.Writer, tabular Tabular) error {
+	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0) //nolint:mnd
+
+	fmt.Fprintln(table, strings.Join(tabular.Header(), "\t"))
+
+	for _, row := range tabular.Rows() {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+
+	return errors.Wrap(table.Flush(), "error writing table")
+}
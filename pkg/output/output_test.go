@@ -0,0 +1,87 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package output_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/output"
+)
+
+type result struct {
+	Name     string `json:"name"     yaml:"name"`
+	ExitCode int    `json:"exitCode" yaml:"exitCode"`
+}
+
+type results []result
+
+func (r results) Header() []string {
+	return []string{"NAME", "EXIT-CODE"}
+}
+
+func (r results) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, item := range r {
+		rows = append(rows, []string{item.Name, "0"})
+	}
+
+	return rows
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	value := results{{Name: "master-1"}, {Name: "worker-10"}}
+
+	tests := map[output.Format]string{
+		output.FormatJSON:  "[\n  {\n    \"name\": \"master-1\",\n    \"exitCode\": 0\n  },\n  {\n    \"name\": \"worker-10\",\n    \"exitCode\": 0\n  }\n]\n", //nolint:lll
+		output.FormatYAML:  "- name: master-1\n  exitCode: 0\n- name: worker-10\n  exitCode: 0\n",
+		output.FormatTable: "NAME        EXIT-CODE\nmaster-1    0\nworker-10   0\n",
+	}
+
+	for format, want := range tests {
+		var b bytes.Buffer
+
+		if err := output.Write(&b, format, value); err != nil {
+			t.Fatal(err)
+		}
+
+		if b.String() != want {
+			t.Fatalf("format=%s, want=%q, got=%q", format, want, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := output.Write(&b, output.FormatTable, result{Name: "master-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "name: master-1\nexitCode: 0\n" {
+		t.Fatalf("unexpected %q", b.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	if format, err := output.ParseFormat("JSON"); err != nil || format != output.FormatJSON {
+		t.Fatalf("unexpected format=%s, err=%v", format, err)
+	}
+
+	if _, err := output.ParseFormat("xml"); err == nil {
+		t.Fatal("error expected")
+	}
+}
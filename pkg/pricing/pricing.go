@@ -0,0 +1,154 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pricing
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Hetzner Cloud bills in euro
+	defaultCurrency = "EUR"
+	fileMode        = 0o644
+)
+
+var errPriceNotFound = errors.New("price not found")
+
+// Price is monthly price without (net) and with (gross) VAT.
+type Price struct {
+	Net   float64 `json:"net"`
+	Gross float64 `json:"gross"`
+}
+
+// Add returns sum of prices multiplied by count.
+func (p Price) Add(price Price, count int) Price {
+	return Price{
+		Net:   p.Net + price.Net*float64(count),
+		Gross: p.Gross + price.Gross*float64(count),
+	}
+}
+
+// Snapshot is monthly prices of server and load balancer types by location.
+type Snapshot struct {
+	Currency          string                      `json:"currency"`
+	CreatedAt         time.Time                   `json:"createdAt"`
+	ServerTypes       map[string]map[string]Price `json:"serverTypes"`
+	LoadBalancerTypes map[string]map[string]Price `json:"loadBalancerTypes"`
+}
+
+// FromHcloud creates snapshot from server and load balancer types of Hetzner Cloud API.
+func FromHcloud(serverTypes []*hcloud.ServerType, loadBalancerTypes []*hcloud.LoadBalancerType) (*Snapshot, error) {
+	snapshot := Snapshot{
+		Currency:          defaultCurrency,
+		CreatedAt:         time.Now().UTC().Truncate(time.Second),
+		ServerTypes:       make(map[string]map[string]Price),
+		LoadBalancerTypes: make(map[string]map[string]Price),
+	}
+
+	for _, serverType := range serverTypes {
+		snapshot.ServerTypes[serverType.Name] = make(map[string]Price)
+
+		for _, pricing := range serverType.Pricings {
+			price, err := snapshot.parsePrice(pricing.Monthly)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid price of server type %s", serverType.Name)
+			}
+
+			snapshot.ServerTypes[serverType.Name][pricing.Location.Name] = price
+		}
+	}
+
+	for _, loadBalancerType := range loadBalancerTypes {
+		snapshot.LoadBalancerTypes[loadBalancerType.Name] = make(map[string]Price)
+
+		for _, pricing := range loadBalancerType.Pricings {
+			price, err := snapshot.parsePrice(pricing.Monthly)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid price of load balancer type %s", loadBalancerType.Name)
+			}
+
+			snapshot.LoadBalancerTypes[loadBalancerType.Name][pricing.Location.Name] = price
+		}
+	}
+
+	return &snapshot, nil
+}
+
+func (s *Snapshot) parsePrice(price hcloud.Price) (Price, error) {
+	if len(price.Currency) > 0 {
+		s.Currency = price.Currency
+	}
+
+	net, err := strconv.ParseFloat(price.Net, 64)
+	if err != nil {
+		return Price{}, errors.Wrap(err, "error parsing net price")
+	}
+
+	gross, err := strconv.ParseFloat(price.Gross, 64)
+	if err != nil {
+		return Price{}, errors.Wrap(err, "error parsing gross price")
+	}
+
+	return Price{Net: net, Gross: gross}, nil
+}
+
+// Load reads snapshot from file.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading pricing snapshot")
+	}
+
+	snapshot := Snapshot{}
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, errors.Wrap(err, "error parsing pricing snapshot")
+	}
+
+	return &snapshot, nil
+}
+
+// Save writes snapshot to file.
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling pricing snapshot")
+	}
+
+	return errors.Wrap(os.WriteFile(path, data, fileMode), "error writing pricing snapshot")
+}
+
+// ServerType returns monthly price of server type in location.
+func (s *Snapshot) ServerType(name, location string) (Price, error) {
+	return findPrice(s.ServerTypes, name, location)
+}
+
+// LoadBalancerType returns monthly price of load balancer type in location.
+func (s *Snapshot) LoadBalancerType(name, location string) (Price, error) {
+	return findPrice(s.LoadBalancerTypes, name, location)
+}
+
+func findPrice(prices map[string]map[string]Price, name, location string) (Price, error) {
+	price, ok := prices[name][location]
+	if !ok {
+		return Price{}, errors.Wrapf(errPriceNotFound, "%s in %s", name, location)
+	}
+
+	return price, nil
+}
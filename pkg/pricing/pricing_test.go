@@ -0,0 +1,87 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pricing_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/pricing"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	hel1 := &hcloud.Location{Name: "hel1"}
+
+	snapshot, err := pricing.FromHcloud(
+		[]*hcloud.ServerType{{
+			Name: "cx23",
+			Pricings: []hcloud.ServerTypeLocationPricing{{
+				Location: hel1,
+				Monthly:  hcloud.Price{Net: "3.4900000000", Gross: "4.1531000000"},
+			}},
+		}},
+		[]*hcloud.LoadBalancerType{{
+			Name: "lb11",
+			Pricings: []hcloud.LoadBalancerTypeLocationPricing{{
+				Location: hel1,
+				Monthly:  hcloud.Price{Net: "5.3900000000", Gross: "6.4141000000"},
+			}},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "pricing.json")
+
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := pricing.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := loaded.ServerType("cx23", "hel1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadBalancer, err := loaded.LoadBalancerType("lb11", "hel1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total := (pricing.Price{}).Add(server, 3).Add(loadBalancer, 1); total.Net < 15.85 || total.Net > 15.87 {
+		t.Fatalf("unexpected total %+v", total)
+	}
+
+	if loaded.Currency != "EUR" || !loaded.CreatedAt.Equal(snapshot.CreatedAt) {
+		t.Fatalf("unexpected snapshot %+v", loaded)
+	}
+
+	if _, err := loaded.ServerType("cx23", "fsn1"); err == nil {
+		t.Fatal("error expected")
+	}
+
+	if _, err := pricing.FromHcloud([]*hcloud.ServerType{{
+		Name:     "cx23",
+		Pricings: []hcloud.ServerTypeLocationPricing{{Location: hel1, Monthly: hcloud.Price{Net: "invalid"}}},
+	}}, nil); err == nil {
+		t.Fatal("error expected")
+	}
+}
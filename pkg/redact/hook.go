@@ -0,0 +1,53 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package redact
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Hook masks secrets in log messages and fields.
+type Hook struct {
+	redactor *redactor
+}
+
+// NewHook returns hook that masks all secrets added to package.
+func NewHook() *Hook {
+	return &Hook{redactor: defaultRedactor}
+}
+
+func (h *Hook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *Hook) Fire(entry *log.Entry) error {
+	entry.Message = h.redactor.String(entry.Message)
+
+	for key, value := range entry.Data {
+		switch typed := value.(type) {
+		case string:
+			entry.Data[key] = h.redactor.String(typed)
+		case error:
+			if text := h.redactor.String(typed.Error()); text != typed.Error() {
+				entry.Data[key] = errors.New(text) //nolint:err113
+			}
+		case fmt.Stringer:
+			entry.Data[key] = h.redactor.String(typed.String())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	Mask = "<secret>"
+	// shorter values are not masked to not hide everything.
+	minSecretLength = 4
+)
+
+// DefaultKeys are yaml keys with sensitive values.
+//
+//nolint:gochecknoglobals
+var DefaultKeys = []string{
+	"hetznerToken",
+	"token",
+	"password",
+	"secret",
+	"secretKey",
+	"client-key-data",
+}
+
+//nolint:gochecknoglobals
+var patterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// private keys in PEM format
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?-----END [A-Z0-9 ]*PRIVATE KEY-----`), Mask},
+	// kubeadm bootstrap tokens
+	{regexp.MustCompile(`\b[a-z0-9]{6}\.[a-z0-9]{16}\b`), Mask},
+	// kubeadm certificate key to download control-plane certificates
+	{regexp.MustCompile(`(--certificate-key[ =])[a-f0-9]{64}`), "${1}" + Mask},
+	// bearer tokens in headers
+	{regexp.MustCompile(`(?i)(authorization:\s*bearer\s+)\S+`), "${1}" + Mask},
+}
+
+type redactor struct {
+	mutex   sync.RWMutex
+	secrets []string
+	keys    map[string]bool
+	keysRe  *regexp.Regexp
+}
+
+//nolint:gochecknoglobals
+var defaultRedactor = newRedactor()
+
+func newRedactor() *redactor {
+	r := &redactor{keys: make(map[string]bool)}
+	r.addKeys(DefaultKeys...)
+
+	return r
+}
+
+// AddSecret adds values that will be masked everywhere.
+func AddSecret(values ...string) {
+	defaultRedactor.addSecret(values...)
+}
+
+// AddKeys adds yaml keys with sensitive values.
+func AddKeys(keys ...string) {
+	defaultRedactor.addKeys(keys...)
+}
+
+// AddSecretsFrom walks maps and slices and adds values of sensitive keys as secrets.
+func AddSecretsFrom(values interface{}) {
+	defaultRedactor.addSecretsFrom(values)
+}
+
+// String returns text with masked secrets.
+func String(text string) string {
+	return defaultRedactor.String(text)
+}
+
+func (r *redactor) addSecret(values ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+
+		if len(value) < minSecretLength {
+			continue
+		}
+
+		r.secrets = append(r.secrets, value)
+	}
+
+	// longest first, secret can contain other secret
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+}
+
+func (r *redactor) addKeys(keys ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, key := range keys {
+		if len(key) > 0 {
+			r.keys[strings.ToLower(key)] = true
+		}
+	}
+
+	quoted := make([]string, 0, len(r.keys))
+
+	for key := range r.keys {
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+
+	sort.Strings(quoted)
+
+	// yaml `key: value` and shell `key=value`
+	r.keysRe = regexp.MustCompile(`(?im)(^|[\s{,"'])((?:` + strings.Join(quoted, "|") + `)["']?\s*[:=][ \t]*["']?)[^\s,}"']+`)
+}
+
+func (r *redactor) isKey(key string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.keys[strings.ToLower(key)]
+}
+
+func (r *redactor) addSecretsFrom(values interface{}) {
+	switch typed := values.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			r.addSecretFrom(key, value)
+		}
+	case map[interface{}]interface{}:
+		for key, value := range typed {
+			if keyString, ok := key.(string); ok {
+				r.addSecretFrom(keyString, value)
+			}
+		}
+	case []interface{}:
+		for _, value := range typed {
+			r.addSecretsFrom(value)
+		}
+	}
+}
+
+func (r *redactor) addSecretFrom(key string, value interface{}) {
+	if text, ok := value.(string); ok && r.isKey(key) {
+		r.addSecret(text)
+
+		return
+	}
+
+	r.addSecretsFrom(value)
+}
+
+func (r *redactor) String(text string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, Mask)
+	}
+
+	for _, pattern := range patterns {
+		text = pattern.re.ReplaceAllString(text, pattern.repl)
+	}
+
+	return r.keysRe.ReplaceAllString(text, "${1}${2}"+Mask)
+}
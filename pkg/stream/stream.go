@@ -0,0 +1,174 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/redact"
+	"github.com/pkg/errors"
+)
+
+const (
+	logFileMode = 0o600
+	runDirMode  = 0o700
+)
+
+var unsafeFileName = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Output writes lines from many hosts to one writer and to per host log files,
+// all lines are redacted.
+type Output struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	runDir string
+	files  map[string]*os.File
+}
+
+// NewOutput creates output, out can be nil to skip console output,
+// runDir can be empty to skip log files.
+func NewOutput(out io.Writer, runDir string) (*Output, error) {
+	if len(runDir) > 0 {
+		if err := os.MkdirAll(runDir, runDirMode); err != nil {
+			return nil, errors.Wrap(err, "error creating run directory")
+		}
+	}
+
+	return &Output{
+		out:    out,
+		runDir: runDir,
+		files:  make(map[string]*os.File),
+	}, nil
+}
+
+// RunDir returns unique directory for current run.
+func RunDir(baseDir, action string) string {
+	return filepath.Join(baseDir, fmt.Sprintf("%s-%s", action, time.Now().Format("20060102150405")))
+}
+
+// Writer returns writer that prefixes each line with host name.
+func (o *Output) Writer(host string) *LineWriter {
+	return &LineWriter{
+		line: func(line string) {
+			o.WriteLine(host, line)
+		},
+	}
+}
+
+// WriteLine writes one line of host output.
+func (o *Output) WriteLine(host, line string) {
+	line = redact.String(line)
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.out != nil {
+		_, _ = fmt.Fprintf(o.out, "%s | %s\n", host, line)
+	}
+
+	if file := o.file(host); file != nil {
+		_, _ = fmt.Fprintf(file, "%s %s\n", time.Now().Format(time.RFC3339), line)
+	}
+}
+
+// file returns opened log file of host, must be called with lock.
+func (o *Output) file(host string) *os.File {
+	if len(o.runDir) == 0 {
+		return nil
+	}
+
+	if file, ok := o.files[host]; ok {
+		return file
+	}
+
+	path := filepath.Join(o.runDir, unsafeFileName.ReplaceAllString(host, "_")+".log")
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, logFileMode)
+	if err != nil {
+		// do not retry on every line
+		o.files[host] = nil
+
+		return nil
+	}
+
+	o.files[host] = file
+
+	return file
+}
+
+// Close closes all log files.
+func (o *Output) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var result error
+
+	for host, file := range o.files {
+		if file == nil {
+			continue
+		}
+
+		if err := file.Close(); err != nil && result == nil {
+			result = errors.Wrap(err, "error closing log file")
+		}
+
+		delete(o.files, host)
+	}
+
+	return result
+}
+
+// LineWriter calls line function for each complete line,
+// last incomplete line is written on Flush.
+type LineWriter struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+	line   func(string)
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buffer.Write(p)
+
+	for {
+		index := bytes.IndexByte(w.buffer.Bytes(), '\n')
+		if index < 0 {
+			break
+		}
+
+		line := string(bytes.TrimRight(w.buffer.Next(index+1), "\r\n"))
+
+		w.line(line)
+	}
+
+	return len(p), nil
+}
+
+func (w *LineWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.buffer.Len() > 0 {
+		w.line(w.buffer.String())
+		w.buffer.Reset()
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package stream_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/stream"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	runDir := filepath.Join(t.TempDir(), "run")
+
+	output, err := stream.NewOutput(&out, runDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := output.Writer("master-1")
+
+	_, _ = writer.Write([]byte("first line\nsecond"))
+	_, _ = writer.Write([]byte(" line\r\ntoken: abcdef.0123456789abcdef\nlast"))
+
+	if strings.Contains(out.String(), "last") {
+		t.Fatal("incomplete line must not be written")
+	}
+
+	writer.Flush()
+
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "master-1 | first line\nmaster-1 | second line\nmaster-1 | token: <secret>\nmaster-1 | last\n"
+
+	if out.String() != want {
+		t.Fatalf("want=%q, got=%q", want, out.String())
+	}
+
+	logFile, err := os.ReadFile(filepath.Join(runDir, "master-1.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(logFile), "\n"); lines != 4 {
+		t.Fatalf("unexpected log file %s", logFile)
+	}
+
+	if strings.Contains(string(logFile), "0123456789abcdef") {
+		t.Fatal("secret in log file")
+	}
+}
+
+func TestOutputConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	output, err := stream.NewOutput(&out, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			writer := output.Writer(fmt.Sprintf("server-%d", i))
+
+			for range 100 {
+				_, _ = writer.Write([]byte("some output\n"))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if !strings.HasPrefix(line, "server-") || !strings.HasSuffix(line, " | some output") {
+			t.Fatalf("lines are mixed %q", line)
+		}
+	}
+}
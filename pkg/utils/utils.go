@@ -14,6 +14,7 @@ package utils
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -37,3 +38,8 @@ func Filter[T any](a []T, f func(T) bool) []T {
 
 	return result
 }
+
+// ShellQuote returns value in single quotes, value is not expanded by shell.
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
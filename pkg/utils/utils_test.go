@@ -0,0 +1,35 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/maksim-paskal/hcloud-k8s-ctl/pkg/utils"
+)
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"/etc/foo", "/etc/foo$bar", "/etc/`id`", `/etc/a\b`, "/etc/it's", "/etc/a b"} {
+		out, err := exec.Command("bash", "-c", "printf %s "+utils.ShellQuote(value)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(out) != value {
+			t.Fatalf("want=%s, got=%s", value, string(out))
+		}
+	}
+}
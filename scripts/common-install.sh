@@ -27,6 +27,17 @@ export PAUSE_CONTAINER="{{ .Values.serverComponents.containerd.pausecontainer }}
 export DEBIAN_FRONTEND=noninteractive
 export HOME=/root/
 
+# image built by build-image action has all packages installed
+IMAGE_MARKER=/etc/hcloud-k8s-ctl/image-version
+IMAGE_VERSION="kubernetes=$KUBERNETES_VERSION docker=$DOCKER_VERSION containerd=$CONTAINERD_VERSION"
+INSTALL_PACKAGES=true
+
+if [ "$(cat $IMAGE_MARKER 2>/dev/null)" == "$IMAGE_VERSION" ]; then
+  echo "Packages are installed in image, skipping install"
+  INSTALL_PACKAGES=false
+fi
+
+if [ "$INSTALL_PACKAGES" == "true" ]; then
 # uninstall old versions if exists
 dpkg --purge docker docker-engine docker.io containerd runc
 
@@ -43,6 +54,7 @@ software-properties-common \
 nfs-common \
 linux-headers-generic \
 lsb-release
+fi
 
 # create new user to ssh into server
 hcloud_user="{{ .Values.serverComponents.ubuntu.username }}"
@@ -71,7 +83,8 @@ PrintMotd no
 AcceptEnv LANG LC_*
 Subsystem sftp /usr/lib/openssh/sftp-server
 
-AllowTcpForwarding no
+# local forwarding is used by -ssh.forward, remote forwarding stays disabled
+AllowTcpForwarding local
 X11Forwarding no
 AllowAgentForwarding no
 EOF
@@ -88,6 +101,7 @@ swapoff -a
 systemctl stop rpcbind.service rpcbind.socket rpcbind.target
 systemctl disable rpcbind.service rpcbind.socket rpcbind.target
 
+if [ "$INSTALL_PACKAGES" == "true" ]; then
 mkdir -p /etc/apt/keyrings
 
 rm -rf /usr/share/keyrings/docker-archive-keyring.gpg /usr/share/keyrings/kubernetes-archive-keyring.gpg
@@ -132,6 +146,16 @@ apt-mark unhold docker-ce docker-ce-cli containerd.io
 apt install -y docker-ce=$DOCKER_VERSION docker-ce-cli=$DOCKER_VERSION containerd.io=$CONTAINERD_VERSION
 apt-mark hold docker-ce docker-ce-cli containerd.io
 
+apt-mark unhold kubelet kubeadm kubectl
+apt-get install -y kubelet=${KUBERNETES_VERSION} kubeadm=${KUBERNETES_VERSION} kubectl=${KUBERNETES_VERSION}
+apt-mark hold kubelet kubeadm kubectl
+
+if [ "$NODE_ROLE" == "image-builder" ]; then
+  mkdir -p "$(dirname $IMAGE_MARKER)"
+  echo "$IMAGE_VERSION" > $IMAGE_MARKER
+fi
+fi
+
 mkdir -p /etc/docker/
 cat <<EOF | tee /etc/docker/daemon.json
 {
@@ -216,14 +240,14 @@ kernel.panic_on_oops=1
 EOF
 sysctl -p
 
-apt-mark unhold kubelet kubeadm kubectl
-apt-get install -y kubelet=${KUBERNETES_VERSION} kubeadm=${KUBERNETES_VERSION} kubectl=${KUBERNETES_VERSION}
-apt-mark hold kubelet kubeadm kubectl
-
 # stop all services
 systemctl stop kubelet containerd docker docker.socket
 
 INTERNAL_IP=$(hostname -I | awk '{print $2}')
+NODE_NAME=$(hostname)
+
+# role is set by hcloud-k8s-ctl, nodes created by autoscaler are workers
+: ${NODE_ROLE="worker"}
 
 mkdir -p /etc/kubernetes/kubelet/
 
@@ -252,12 +276,35 @@ EOF
 # latest ubuntu versions use /etc/default/kubelet
 cp /etc/default/kubelet /etc/sysconfig/kubelet || true
 
+if [ "$INSTALL_PACKAGES" == "true" ]; then
 apt -y autoremove
 apt -y autoclean
+fi
 
 # prestart script
 {{ .Values.preStartScript }}
 
+# role prestart scripts
+if [ "$NODE_ROLE" == "first-master" ] || [ "$NODE_ROLE" == "joining-master" ]; then
+:
+{{ .Values.hooks.masters.preStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "first-master" ]; then
+:
+{{ .Values.hooks.firstMaster.preStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "joining-master" ]; then
+:
+{{ .Values.hooks.joiningMasters.preStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "worker" ]; then
+:
+{{ .Values.hooks.workers.preStartScript }}
+fi
+
 # start all node services
 systemctl daemon-reload
 systemctl enable kubelet containerd docker docker.socket
@@ -268,3 +315,24 @@ ctr --namespace k8s.io image pull $PAUSE_CONTAINER
 
 # poststart script
 {{ .Values.postStartScript }}
+
+# role poststart scripts
+if [ "$NODE_ROLE" == "first-master" ] || [ "$NODE_ROLE" == "joining-master" ]; then
+:
+{{ .Values.hooks.masters.postStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "first-master" ]; then
+:
+{{ .Values.hooks.firstMaster.postStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "joining-master" ]; then
+:
+{{ .Values.hooks.joiningMasters.postStartScript }}
+fi
+
+if [ "$NODE_ROLE" == "worker" ]; then
+:
+{{ .Values.hooks.workers.postStartScript }}
+fi
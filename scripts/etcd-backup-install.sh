@@ -0,0 +1,26 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -ex
+
+# install scheduled etcd snapshots shipping on master node
+install -m 700 /root/scripts/etcd-snapshot.sh /usr/local/sbin/etcd-snapshot.sh
+install -m 700 /root/scripts/etcd-backup.sh /usr/local/sbin/etcd-backup.sh
+install -m 644 /root/scripts/etcd-backup.service /etc/systemd/system/etcd-backup.service
+install -m 644 /root/scripts/etcd-backup.timer /etc/systemd/system/etcd-backup.timer
+
+systemctl daemon-reload
+systemctl enable etcd-backup.timer
+systemctl restart etcd-backup.timer
@@ -1,4 +1,4 @@
-#!/bin/sh
+#!/usr/bin/env bash
 
 # Copyright paskal.maksim@gmail.com
 #
@@ -13,32 +13,58 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License.
+# creates etcd snapshot and uploads it to S3 compatible storage,
+# installed to /usr/local/sbin/etcd-backup.sh by etcd-backup-install.sh
+# do not print commands, script contains credentials
+set -e
 
-# /usr/local/sbin/etcd-backup.sh
-# chmod +x /usr/local/sbin/etcd-backup.sh
-set -ex
-export ETCDCTL_API=3
-BACKUP_DIR="/var/lib/etcd/backups"
-BACKUP_NAME="etcd-snapshot-$(date +%Y%m%d%H%M%S).db"
-
-mkdir -p $BACKUP_DIR
-
-# Defragment the etcd database before taking a snapshot
-etcdctl defrag --cluster \
---endpoints=https://127.0.0.1:2379 \
---cacert=/etc/kubernetes/pki/etcd/ca.crt \
---cert=/etc/kubernetes/pki/etcd/server.crt \
---key=/etc/kubernetes/pki/etcd/server.key
-
-# Take a snapshot of the etcd database
-etcdctl snapshot save $BACKUP_DIR/$BACKUP_NAME \
---endpoints=https://127.0.0.1:2379 \
---cacert=/etc/kubernetes/pki/etcd/ca.crt \
---cert=/etc/kubernetes/pki/etcd/server.crt \
---key=/etc/kubernetes/pki/etcd/server.key
-
-# Optional: Remove backups older than 7 days
-# find $BACKUP_DIR -type f -name "etcd-snapshot-*.db" -mtime +7 -exec rm {} \;
-
-# Optional: Leave only last 5 backups
-ls -1t $BACKUP_DIR/etcd-snapshot-*.db | tail -n +6 | xargs -r rm --
\ No newline at end of file
+ENDPOINT="{{ .Values.backup.endpoint }}"
+REGION="{{ .Values.backup.region }}"
+BUCKET="{{ .Values.backup.bucket }}"
+PREFIX="{{ .Values.backup.prefix }}"
+ACCESS_KEY="{{ .Values.backup.accesskey }}"
+SECRET_KEY="{{ .Values.backup.secretkey }}"
+RETENTION="{{ .Values.backup.retention }}"
+
+# sha256 of empty body
+EMPTY_PAYLOAD="e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+s3() {
+  PAYLOAD=$1
+  shift
+
+  curl --fail --silent --show-error \
+  --aws-sigv4 "aws:amz:$REGION:s3" \
+  --user "$ACCESS_KEY:$SECRET_KEY" \
+  -H "x-amz-content-sha256: $PAYLOAD" \
+  "$@"
+}
+
+# timer is installed on all master nodes, only kube-controller-manager leader uploads snapshot,
+# snapshot is uploaded if leader is unknown
+HOLDER=$(kubectl --kubeconfig /etc/kubernetes/admin.conf -n kube-system get lease kube-controller-manager -o jsonpath='{.spec.holderIdentity}' || true)
+
+if [ -n "$HOLDER" ] && [[ "$HOLDER" != "$(hostname)_"* ]]; then
+  echo "$HOLDER is a leader, skipping"
+  exit 0
+fi
+
+SNAPSHOT_INFO=$(/usr/local/sbin/etcd-snapshot.sh)
+SNAPSHOT_SHA256=$(echo "$SNAPSHOT_INFO" | awk '{print $1}')
+SNAPSHOT=$(echo "$SNAPSHOT_INFO" | awk '{print $2}')
+KEY="$PREFIX/$(basename "$SNAPSHOT")"
+
+echo "Uploading $SNAPSHOT to $BUCKET/$KEY"
+
+s3 "$SNAPSHOT_SHA256" --upload-file "$SNAPSHOT" "$ENDPOINT/$BUCKET/$KEY"
+
+# snapshot names contain date, remove oldest snapshots from bucket
+s3 "$EMPTY_PAYLOAD" "$ENDPOINT/$BUCKET?list-type=2&prefix=$PREFIX%2Fetcd-snapshot-" \
+| grep -o '<Key>[^<]*</Key>' \
+| sed -e 's/<Key>//' -e 's/<\/Key>//' \
+| sort \
+| head -n -"$RETENTION" \
+| while read -r OLD_KEY; do
+  echo "Deleting $BUCKET/$OLD_KEY"
+  s3 "$EMPTY_PAYLOAD" -X DELETE "$ENDPOINT/$BUCKET/$OLD_KEY"
+done
@@ -0,0 +1,127 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -ex
+
+# restores etcd member on current master node from snapshot,
+# executed by restore-etcd action on all master nodes step by step
+MANIFESTS_DIR="/etc/kubernetes/manifests"
+STOPPED_MANIFESTS_DIR="/etc/kubernetes/manifests-restore"
+RESTORE_DIR="/var/lib/etcd-restore"
+ETCD_DATA_DIR="/var/lib/etcd"
+# restore token and path to previous etcd data of swapped member, used by rollback
+ROLLBACK_FILE="/var/lib/etcd-restore-rollback"
+
+CRICTL="crictl --runtime-endpoint unix:///run/containerd/containerd.sock"
+
+# prints value of etcd flag from static pod manifest
+etcd_flag() {
+  grep -oP -- "--$1=\K\S+" "$2/etcd.yaml"
+}
+
+case "$1" in
+member)
+  # print member name and peer url for initial cluster
+  echo "$(etcd_flag name $MANIFESTS_DIR)=$(etcd_flag initial-advertise-peer-urls $MANIFESTS_DIR)"
+  ;;
+stop)
+  # static pods are stopped by kubelet after manifests are removed
+  mkdir -p $STOPPED_MANIFESTS_DIR
+  if ls $MANIFESTS_DIR/*.yaml; then
+    mv $MANIFESTS_DIR/*.yaml $STOPPED_MANIFESTS_DIR/
+  fi
+
+  for i in $(seq 1 60); do
+    if [ -z "$($CRICTL ps --name '^(etcd|kube-apiserver)$' -q)" ]; then
+      exit 0
+    fi
+    sleep 5
+  done
+
+  echo "control-plane containers are still running" >&2
+  exit 1
+  ;;
+prepare)
+  : ${INITIAL_CLUSTER:?} ${INITIAL_CLUSTER_TOKEN:?} ${SNAPSHOT_SHA256:?}
+
+  echo "$SNAPSHOT_SHA256  $RESTORE_DIR/snapshot.db" | sha256sum -c
+
+  # etcdutl is not installed on nodes, use binary from etcd image
+  ETCD_IMAGE=$(grep -oP 'image: \K\S+' $STOPPED_MANIFESTS_DIR/etcd.yaml)
+
+  rm -rf $RESTORE_DIR/data
+  ctr --namespace k8s.io run --rm \
+  --mount type=bind,src=$RESTORE_DIR,dst=$RESTORE_DIR,options=rbind:rw \
+  "$ETCD_IMAGE" "etcd-restore-$(date +%s)" \
+  etcdutl snapshot restore $RESTORE_DIR/snapshot.db \
+  --name "$(etcd_flag name $STOPPED_MANIFESTS_DIR)" \
+  --initial-cluster "$INITIAL_CLUSTER" \
+  --initial-cluster-token "$INITIAL_CLUSTER_TOKEN" \
+  --initial-advertise-peer-urls "$(etcd_flag initial-advertise-peer-urls $STOPPED_MANIFESTS_DIR)" \
+  --data-dir $RESTORE_DIR/data
+  ;;
+swap)
+  : ${INITIAL_CLUSTER_TOKEN:?}
+
+  # executed only after prepare succeeded on all master nodes
+  test -d $RESTORE_DIR/data/member
+
+  # keep current data for rollback, snapshots in $ETCD_DATA_DIR/backups stay in place
+  BEFORE_RESTORE_DIR="$ETCD_DATA_DIR-before-restore-$(date +%Y%m%d%H%M%S)"
+  mkdir -p "$BEFORE_RESTORE_DIR"
+  echo "$INITIAL_CLUSTER_TOKEN $BEFORE_RESTORE_DIR" > $ROLLBACK_FILE
+  if [ -d $ETCD_DATA_DIR/member ]; then
+    mv $ETCD_DATA_DIR/member "$BEFORE_RESTORE_DIR/"
+  fi
+  mv $RESTORE_DIR/data/member $ETCD_DATA_DIR/member
+  rm -rf $RESTORE_DIR
+  ;;
+rollback)
+  : ${INITIAL_CLUSTER_TOKEN:?}
+
+  # returns previous etcd data on member swapped by current restore,
+  # members that were not swapped or swapped by previous restores are not changed
+  ROLLBACK_TOKEN=""
+  if [ -f $ROLLBACK_FILE ]; then
+    read -r ROLLBACK_TOKEN BEFORE_RESTORE_DIR < $ROLLBACK_FILE
+  fi
+  if [ "$ROLLBACK_TOKEN" == "$INITIAL_CLUSTER_TOKEN" ]; then
+    rm -rf $ETCD_DATA_DIR/member
+    if [ -d "$BEFORE_RESTORE_DIR/member" ]; then
+      mv "$BEFORE_RESTORE_DIR/member" $ETCD_DATA_DIR/member
+    fi
+    rm -f $ROLLBACK_FILE
+  fi
+  rm -rf $RESTORE_DIR
+  ;;
+start)
+  # also used to recover control-plane after failed restore
+  if [ -d $STOPPED_MANIFESTS_DIR ]; then
+    if ls $STOPPED_MANIFESTS_DIR/*.yaml; then
+      mv $STOPPED_MANIFESTS_DIR/*.yaml $MANIFESTS_DIR/
+    fi
+    rmdir $STOPPED_MANIFESTS_DIR
+  fi
+  systemctl restart kubelet
+  ;;
+health)
+  # api server is checked through load balancer
+  kubectl --kubeconfig /etc/kubernetes/admin.conf get --raw=/readyz
+  ;;
+*)
+  echo "usage: $0 member|stop|prepare|swap|rollback|start|health" >&2
+  exit 1
+  ;;
+esac
@@ -0,0 +1,55 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -ex
+
+# creates etcd snapshot on current master node, prints snapshot sha256 and path
+BACKUP_DIR="/var/lib/etcd/backups"
+BACKUP_NAME="etcd-snapshot-$(date +%Y%m%d%H%M%S)-$(hostname).db"
+
+# number of snapshots to keep on node
+: ${BACKUP_KEEP=5}
+
+CRICTL="crictl --runtime-endpoint unix:///run/containerd/containerd.sock"
+
+# etcdctl is not installed on nodes, use binary from etcd static pod
+ETCD_CONTAINER=$($CRICTL ps --name '^etcd$' --state running -q | head -1)
+
+if [ -z "$ETCD_CONTAINER" ]; then
+  echo "etcd container is not running" >&2
+  exit 1
+fi
+
+etcdctl() {
+  $CRICTL exec "$ETCD_CONTAINER" etcdctl \
+  --endpoints=https://127.0.0.1:2379 \
+  --cacert=/etc/kubernetes/pki/etcd/ca.crt \
+  --cert=/etc/kubernetes/pki/etcd/server.crt \
+  --key=/etc/kubernetes/pki/etcd/server.key \
+  "$@"
+}
+
+# do not create snapshot from unhealthy member
+etcdctl endpoint health >&2
+
+# etcd data directory is mounted to etcd container with same path
+mkdir -p $BACKUP_DIR
+etcdctl snapshot save "$BACKUP_DIR/$BACKUP_NAME" >&2
+chmod 600 "$BACKUP_DIR/$BACKUP_NAME"
+
+# keep only last snapshots on node
+ls -1t $BACKUP_DIR/etcd-snapshot-*.db | tail -n +$((BACKUP_KEEP + 1)) | xargs -r rm --
+
+sha256sum "$BACKUP_DIR/$BACKUP_NAME"
@@ -30,11 +30,6 @@ networking:
   podSubnet: "10.244.0.0/16" # --pod-network-cidr
 EOF
 
-kubeadm init --upload-certs --config=/root/scripts/kubeadm-config.yaml --v=10
+kubeadm init --config=/root/scripts/kubeadm-config.yaml --v=10
 
-
-# create join command to join to the cluster
-CERTIFICATE_KEY=$(kubeadm init phase upload-certs --upload-certs | tail -1)
-JOIN=$(kubeadm token create --print-join-command --certificate-key="$CERTIFICATE_KEY")
-
-echo "$JOIN --cri-socket=unix:///run/containerd/containerd.sock" > /root/scripts/join-master.sh
+# join tokens and certificate keys are created on demand by join-token.sh
@@ -0,0 +1,24 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -ex
+
+# install refresh of worker join token on master node, only leader master refreshes token
+install -m 644 /root/scripts/join-token-refresh.service /etc/systemd/system/join-token-refresh.service
+install -m 644 /root/scripts/join-token-refresh.timer /etc/systemd/system/join-token-refresh.timer
+
+systemctl daemon-reload
+systemctl enable join-token-refresh.timer
+systemctl restart join-token-refresh.timer
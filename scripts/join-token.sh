@@ -0,0 +1,80 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -e
+
+# join material is printed to stdout and never saved on disk, no tracing to not log it
+# usage: join-token.sh create|delete <token>|worker|refresh-worker
+: "${JOIN_TOKEN_TTL:=15m}"
+# worker token is refreshed by join-token-refresh.timer every 12h
+: "${WORKER_JOIN_TOKEN_TTL:=24h}"
+
+CRI_SOCKET=unix:///run/containerd/containerd.sock
+KUBECONFIG=/etc/kubernetes/admin.conf
+AUTOSCALER_DEPLOYMENT=hcloud-k8s-ctl-hetzner-cluster-autoscaler
+
+export KUBECONFIG
+
+# creates worker token with limited ttl, cloud-init with join command is stored only in hcloud-init configmap
+createWorkerToken() {
+  JOIN=$(kubeadm token create --print-join-command --ttl="$WORKER_JOIN_TOKEN_TTL" --description="hcloud-k8s-ctl worker join")
+
+  HCLOUD_CLOUD_INIT=$( (cat /root/scripts/common-install.sh; echo "$JOIN --cri-socket=$CRI_SOCKET") | base64 -w 0)
+
+  kubectl -n kube-system create configmap hcloud-init \
+  --from-literal=bootstrap="$HCLOUD_CLOUD_INIT" \
+  --dry-run=client -o yaml | kubectl apply -f - > /dev/null
+}
+
+# only master that holds kube-controller-manager lease refreshes token
+isLeader() {
+  HOLDER=$(kubectl -n kube-system get lease kube-controller-manager -o jsonpath='{.spec.holderIdentity}')
+
+  [[ "$HOLDER" == "$(hostname)_"* ]]
+}
+
+case "$1" in
+create)
+  # certificates are uploaded to kubeadm-certs secret encrypted with new key
+  CERTIFICATE_KEY=$(kubeadm certs certificate-key)
+  kubeadm init phase upload-certs --upload-certs --certificate-key="$CERTIFICATE_KEY" > /dev/null
+
+  JOIN=$(kubeadm token create --print-join-command --ttl="$JOIN_TOKEN_TTL" --description="hcloud-k8s-ctl join" --certificate-key="$CERTIFICATE_KEY")
+
+  echo "$JOIN --cri-socket=$CRI_SOCKET"
+  ;;
+delete)
+  kubeadm token delete "$2"
+  kubectl -n kube-system delete secret kubeadm-certs --ignore-not-found
+  ;;
+worker)
+  createWorkerToken
+  ;;
+refresh-worker)
+  if ! isLeader; then
+    echo "not a leader, skipping"
+    exit 0
+  fi
+
+  createWorkerToken
+
+  # autoscaler reads cloud-init from environment on start
+  kubectl -n kube-system rollout restart deploy "$AUTOSCALER_DEPLOYMENT"
+  ;;
+*)
+  echo "unknown command $1"
+  exit 1
+  ;;
+esac
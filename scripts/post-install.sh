@@ -20,10 +20,6 @@ set -ex
 
 export KUBECONFIG=$KUBECONFIG_PATH
 
-# create annotation for recent node deletion
-kubectl annotate node --overwrite -lnode-role.kubernetes.io/master cluster-autoscaler.kubernetes.io/scale-down-disabled=true
-kubectl annotate node --overwrite -lnode-role.kubernetes.io/control-plane cluster-autoscaler.kubernetes.io/scale-down-disabled=true
-
 OS_ARCH=$(dpkg --print-architecture)
 
 # install helm for kubernetes deploymens
@@ -36,16 +32,11 @@ rm -rf "linux-${OS_ARCH}"
 # delete all tokens
 kubeadm token list -o jsonpath='{.token}{"\n"}' | xargs kubeadm token delete
 
-# create token to join worker nodes
-cp /root/scripts/common-install.sh /root/scripts/cloud-init.sh
-JOIN=$(kubeadm token create --ttl=0 --print-join-command)
-
-echo "$JOIN --cri-socket=unix:///run/containerd/containerd.sock" >> /root/scripts/cloud-init.sh
+# remove cloud-init with join token saved by previous versions
+rm -f /root/scripts/cloud-init.sh
 
-# create autoscaler configuration
-HCLOUD_CLOUD_INIT=$(base64 -w 0 < /root/scripts/cloud-init.sh)
-kubectl -n kube-system delete configmap hcloud-init || true
-kubectl -n kube-system create configmap hcloud-init --from-literal=bootstrap="$HCLOUD_CLOUD_INIT"
+# create autoscaler configuration with worker join token, token has limited ttl and is refreshed by timer
+/root/scripts/join-token.sh worker
 # shutdown current autoscaler that have wrong cloud-init configuration
 kubectl -n kube-system scale deploy hcloud-k8s-ctl-hetzner-cluster-autoscaler --replicas=0 || true
 
@@ -0,0 +1,46 @@
+#!/usr/bin/env bash
+
+# Copyright paskal.maksim@gmail.com
+#
+# Licensed under the Apache License, Version 2.0 (the "License")
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -e
+
+# prints cluster state for verification after create
+# usage: verify-cluster.sh etcd-members|helm-releases
+CRICTL="crictl --runtime-endpoint unix:///run/containerd/containerd.sock"
+
+case "$1" in
+etcd-members)
+  # etcdctl is not installed on nodes, use binary from etcd static pod
+  ETCD_CONTAINER=$($CRICTL ps --name '^etcd$' --state running -q | head -1)
+
+  if [ -z "$ETCD_CONTAINER" ]; then
+    echo "etcd container is not running" >&2
+    exit 1
+  fi
+
+  $CRICTL exec "$ETCD_CONTAINER" etcdctl \
+  --endpoints=https://127.0.0.1:2379 \
+  --cacert=/etc/kubernetes/pki/etcd/ca.crt \
+  --cert=/etc/kubernetes/pki/etcd/server.crt \
+  --key=/etc/kubernetes/pki/etcd/server.key \
+  member list -w simple
+  ;;
+helm-releases)
+  helm list --all-namespaces --all --output json --kubeconfig /etc/kubernetes/admin.conf
+  ;;
+*)
+  echo "unknown command $1" >&2
+  exit 1
+  ;;
+esac